@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bufio"
+	"bwd/pkg/auth"
+	"bwd/pkg/ledger"
+	"bwd/pkg/secret"
+	"bwd/pkg/storage"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type Config struct {
+	StorageConnectionString string `env:"STORAGE_CONNECTION_STRING" env-default:""`
+	MasterKey               string `env:"MASTER_KEY" env-default:""`
+	MasterKeyFile           string `env:"MASTER_KEY_FILE" env-default:""`
+}
+
+func (c *Config) validate() error {
+	if c.StorageConnectionString == "" {
+		return errors.New("[CONFIG] StorageConnectionString can not be empty")
+	}
+
+	return nil
+}
+
+const usage = `usage: bwdctl <command> [flags]
+
+commands:
+  report                            show withdrawable vs reinvested profit per app
+  withdraw -app ID -amount QUOTE    record a real withdrawal of reserved profit
+  master-key-gen                    print a new random master key
+  account-list                      list exchange accounts
+  account-add -exchange EX -label L add account, api keys are read from stdin and stored encrypted
+  account-rotate -account ID        replace account api keys, read from stdin
+  token-list                        list api tokens
+  token-create -name N -role R      create api token, role READ_ONLY or ADMIN, token is printed once
+  token-revoke -id ID               revoke api token
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// does not need storage
+	if os.Args[1] == "master-key-gen" {
+		key, err := secret.GenerateMasterKey()
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	cfg := &Config{}
+	if err := cleanenv.ReadEnv(cfg); err != nil {
+		fatal(fmt.Errorf("can not read env vars, err: %w", err))
+	}
+
+	if err := cfg.validate(); err != nil {
+		fatal(err)
+	}
+
+	storer, err := storage.NewMysql(cfg.StorageConnectionString)
+	if err != nil {
+		fatal(fmt.Errorf("create mysql instance fail, err: %w", err))
+	}
+
+	switch os.Args[1] {
+	case "report":
+		err = report(storer, os.Args[2:])
+	case "withdraw":
+		err = withdraw(storer, os.Args[2:])
+	case "account-list":
+		err = accountList(storer)
+	case "account-add":
+		err = accountAdd(storer, cfg, os.Args[2:])
+	case "account-rotate":
+		err = accountRotate(storer, cfg, os.Args[2:])
+	case "token-list":
+		err = tokenList(storer)
+	case "token-create":
+		err = tokenCreate(storer, os.Args[2:])
+	case "token-revoke":
+		err = tokenRevoke(storer, os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func report(storer storage.Storer, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	appID := fs.Int("app", 0, "app id, all apps when missing")
+	_ = fs.Parse(args)
+
+	l := ledger.New(storer)
+
+	var reports []ledger.Report
+	if *appID > 0 {
+		r, err := l.AppReport(*appID)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, r)
+	} else {
+		var err error
+		if reports, err = l.Report(); err != nil {
+			return err
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APP\tNET INCOME\tREINVESTED\tRESERVED\tWITHDRAWN\tWITHDRAWABLE\tNOT ALLOCATED")
+	for _, r := range reports {
+		fmt.Fprintf(w, "%d\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			r.AppID,
+			r.TotalNetIncome,
+			r.TotalReinvested,
+			r.TotalReserved,
+			r.TotalWithdrawn,
+			r.Withdrawable,
+			r.NotAllocated,
+		)
+	}
+
+	return w.Flush()
+}
+
+func withdraw(storer storage.Storer, args []string) error {
+	fs := flag.NewFlagSet("withdraw", flag.ExitOnError)
+	appID := fs.Int("app", 0, "app id")
+	amount := fs.Float64("amount", 0, "withdrawn quote volume")
+	_ = fs.Parse(args)
+
+	if *appID < 1 {
+		return errors.New("app id should be greater than 0")
+	}
+
+	if err := ledger.New(storer).Withdraw(*appID, *amount); err != nil {
+		return err
+	}
+
+	fmt.Printf("withdraw of %v recorded for app %d\n", *amount, *appID)
+
+	return nil
+}
+
+func accountList(storer storage.Storer) error {
+	accounts, err := storer.Accounts()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEXCHANGE\tLABEL\tCREDENTIALS")
+	for _, acc := range accounts {
+		creds := "env " + acc.CredentialsRef
+		if acc.APIKeyEnc != "" {
+			creds = "encrypted"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", acc.ID, acc.Exchange, acc.Label, creds)
+	}
+
+	return w.Flush()
+}
+
+func accountAdd(storer storage.Storer, cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("account-add", flag.ExitOnError)
+	exchange := fs.String("exchange", "", "account exchange, eg: BINANCE")
+	label := fs.String("label", "", "unique account label")
+	_ = fs.Parse(args)
+
+	if *exchange == "" || *label == "" {
+		return errors.New("exchange and label can not be empty")
+	}
+
+	apiKeyEnc, secretKeyEnc, err := readEncryptedKeys(cfg)
+	if err != nil {
+		return err
+	}
+
+	id, err := storer.AddAccount(storage.Account{
+		Exchange:     strings.ToUpper(*exchange),
+		Label:        *label,
+		APIKeyEnc:    apiKeyEnc,
+		SecretKeyEnc: secretKeyEnc,
+	})
+	if err != nil {
+		return err
+	}
+
+	audit(storer, "ACCOUNT_ADD", fmt.Sprintf(`{"id":%d,"exchange":%q,"label":%q}`, id, strings.ToUpper(*exchange), *label))
+
+	fmt.Printf("account %d added\n", id)
+
+	return nil
+}
+
+func accountRotate(storer storage.Storer, cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("account-rotate", flag.ExitOnError)
+	accountID := fs.Int("account", 0, "account id")
+	_ = fs.Parse(args)
+
+	if *accountID < 1 {
+		return errors.New("account id should be greater than 0")
+	}
+
+	apiKeyEnc, secretKeyEnc, err := readEncryptedKeys(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := storer.UpdateAccountCredentials(*accountID, apiKeyEnc, secretKeyEnc); err != nil {
+		return err
+	}
+
+	audit(storer, "ACCOUNT_ROTATE", fmt.Sprintf(`{"id":%d}`, *accountID))
+
+	fmt.Printf("account %d keys rotated, bwd restarts account connector on next run\n", *accountID)
+
+	return nil
+}
+
+// readEncryptedKeys reads api and secret key from stdin, keys never appear in
+// args or shell history and are returned encrypted with master key
+func readEncryptedKeys(cfg *Config) (string, string, error) {
+	masterKey, err := secret.LoadMasterKey(cfg.MasterKey, cfg.MasterKeyFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	c, err := secret.New(masterKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	keys := make([]string, 0, 2)
+	for _, name := range []string{"api key", "secret key"} {
+		fmt.Fprintf(os.Stderr, "%s: ", name)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", "", fmt.Errorf("fail read %s, err: %w", name, err)
+		}
+
+		key := strings.TrimSpace(line)
+		if key == "" {
+			return "", "", fmt.Errorf("%s can not be empty", name)
+		}
+
+		enc, err := c.Encrypt(key)
+		if err != nil {
+			return "", "", err
+		}
+		keys = append(keys, enc)
+	}
+
+	return keys[0], keys[1], nil
+}
+
+// cliActor is audit actor of changes made by bwdctl
+func cliActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return "cli:" + user
+	}
+
+	return "cli"
+}
+
+func tokenList(storer storage.Storer) error {
+	tokens, err := storer.APITokens()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tROLE\tCREATED\tREVOKED")
+	for _, t := range tokens {
+		revoked := "-"
+		if !t.RevokedAt.IsZero() {
+			revoked = t.RevokedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Role, t.CreatedAt.Format(time.RFC3339), revoked)
+	}
+
+	return w.Flush()
+}
+
+func tokenCreate(storer storage.Storer, args []string) error {
+	fs := flag.NewFlagSet("token-create", flag.ExitOnError)
+	name := fs.String("name", "", "token owner, recorded in audit log")
+	role := fs.String("role", auth.RoleReadOnly, "READ_ONLY or ADMIN")
+	_ = fs.Parse(args)
+
+	if *name == "" {
+		return errors.New("name can not be empty")
+	}
+
+	r := strings.ToUpper(*role)
+	if !auth.ValidRole(r) {
+		return fmt.Errorf("unknown role: %s", *role)
+	}
+
+	token, hash, err := auth.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	id, err := storer.AddAPIToken(storage.APIToken{
+		Name:      *name,
+		Role:      r,
+		Hash:      hash,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	audit(storer, "TOKEN_CREATE", fmt.Sprintf(`{"id":%d,"name":%q,"role":%q}`, id, *name, r))
+
+	fmt.Fprintf(os.Stderr, "token %d created, it is not stored and can not be shown again\n", id)
+	fmt.Println(token)
+
+	return nil
+}
+
+func tokenRevoke(storer storage.Storer, args []string) error {
+	fs := flag.NewFlagSet("token-revoke", flag.ExitOnError)
+	tokenID := fs.Int("id", 0, "token id")
+	_ = fs.Parse(args)
+
+	if *tokenID < 1 {
+		return errors.New("token id should be greater than 0")
+	}
+
+	if err := storer.RevokeAPIToken(*tokenID); err != nil {
+		return err
+	}
+
+	audit(storer, "TOKEN_REVOKE", fmt.Sprintf(`{"id":%d}`, *tokenID))
+
+	fmt.Printf("token %d revoked\n", *tokenID)
+
+	return nil
+}
+
+// audit records a change made by bwdctl, change is already applied when audit fails
+func audit(storer storage.Storer, action, details string) {
+	err := storer.AddAuditLog(storage.AuditLog{
+		Actor:     cliActor(),
+		Action:    action,
+		Details:   details,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bwdctl: fail add audit log: %s\n", err.Error())
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "bwdctl: %s\n", err.Error())
+	os.Exit(1)
+}
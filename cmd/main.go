@@ -2,12 +2,14 @@ package main
 
 import (
 	"bwd/pkg/bwd"
+	"bwd/pkg/secret"
 	syslog2 "bwd/pkg/utils"
 	"bwd/pkg/utils/metrics/exporter"
 	"context"
 	"errors"
 	"log"
 	"log/syslog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -21,8 +23,12 @@ import (
 type Config struct {
 	Interval                time.Duration `env:"INTERVAL" env-default:""`
 	SlackHook               string        `env:"SLACK_HOOK" env-default:""`
+	NotifyConfig            string        `env:"NOTIFY_CONFIG" env-default:""`
+	DailyReportTime         string        `env:"DAILY_REPORT_TIME" env-default:""`
 	WebBindingPort          string        `env:"WEB_BINDING_PORT" env-default:""`
 	StorageConnectionString string        `env:"STORAGE_CONNECTION_STRING" env-default:""`
+	MasterKey               string        `env:"MASTER_KEY" env-default:""`
+	MasterKeyFile           string        `env:"MASTER_KEY_FILE" env-default:""`
 }
 
 func (c *Config) validate() error {
@@ -42,8 +48,6 @@ func (c *Config) validate() error {
 }
 
 func main() {
-	go exporter.GetExporter("7070")
-
 	logger := logger()
 
 	cfg := &Config{}
@@ -52,7 +56,24 @@ func main() {
 	}
 
 	if err := cfg.validate(); err != nil {
-		logger.WithError(err).Fatalf("invalid config: %+v", cfg)
+		redacted := *cfg
+		if redacted.MasterKey != "" {
+			redacted.MasterKey = "[REDACTED]"
+		}
+		if redacted.SlackHook != "" {
+			redacted.SlackHook = "[REDACTED]"
+		}
+		logger.WithError(err).Fatalf("invalid config: %+v", redacted)
+	}
+
+	// master key is optional, without it only env credentials can be used
+	var masterKey []byte
+	if cfg.MasterKey != "" || cfg.MasterKeyFile != "" {
+		key, err := secret.LoadMasterKey(cfg.MasterKey, cfg.MasterKeyFile)
+		if err != nil {
+			logger.WithError(err).Fatal("can not load master key")
+		}
+		masterKey = key
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -60,18 +81,32 @@ func main() {
 	configBwd := &bwd.ConfigBwd{
 		Interval:                cfg.Interval,
 		SlackHook:               cfg.SlackHook,
+		NotifyConfig:            cfg.NotifyConfig,
+		DailyReportTime:         cfg.DailyReportTime,
 		WebBindingPort:          cfg.WebBindingPort,
 		StorageConnectionString: cfg.StorageConnectionString,
+		MasterKey:               masterKey,
 	}
 
 	b := bwd.New(ctx, configBwd, logger)
+
+	// metrics port also serves probes for process orchestrator, it is up
+	// before start so a slow storage connect does not fail liveness
+	go func() {
+		err := exporter.GetExporter("7070", map[string]http.Handler{
+			"/healthz": b.HealthHandler(),
+			"/readyz":  b.ReadyHandler(),
+		})
+		logger.WithError(err).Error("metrics exporter stopped")
+	}()
+
 	err := b.Start()
 	if err != nil {
 		logger.WithError(err).Fatal("unsuccessful start, everything stopped.")
 	}
 
 	logger.Info("successful start, press Ctrl + C to graceful shutdown")
-	sigint := make(chan os.Signal)
+	sigint := make(chan os.Signal, 1)
 	signal.Notify(sigint, syscall.SIGINT, syscall.SIGTERM)
 	<-sigint
 
@@ -0,0 +1,101 @@
+// notifystub is a local stand-in for notification channels, it prints what bwd sends:
+//
+//	slack:    SLACK_HOOK=http://localhost:8099/hook
+//	telegram: channel url http://localhost:8099, any token and chat_id
+//	webhook:  channel url http://localhost:8099/webhook, secret same as -secret
+package main
+
+import (
+	"bwd/pkg/notify"
+	"crypto/hmac"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	count     int64
+	failEvery int
+)
+
+func main() {
+	port := flag.String("port", "8099", "listen port")
+	secret := flag.String("secret", "", "webhook secret used to verify signature")
+	flag.IntVar(&failEvery, "fail-every", 0, "answer 500 to every n-th message, 0 never fails")
+	flag.Parse()
+
+	http.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			Text string `json:"text"`
+		}
+		if !decode(w, r, &msg) || msg.Text == "" {
+			// same answer as Slack for malformed payloads
+			http.Error(w, "invalid_payload", http.StatusBadRequest)
+			return
+		}
+		reply(w, "slack", msg.Text)
+	})
+
+	http.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "read fail", http.StatusBadRequest)
+			return
+		}
+
+		if *secret != "" {
+			expected := "sha256=" + notify.Sign([]byte(*secret), r.Header.Get(notify.TimestampHeader), body)
+			if !hmac.Equal([]byte(expected), []byte(r.Header.Get(notify.SignatureHeader))) {
+				http.Error(w, "bad signature", http.StatusUnauthorized)
+				return
+			}
+		}
+		reply(w, "webhook", string(body))
+	})
+
+	// telegram bot api: /bot<token>/sendMessage
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/bot") || !strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			http.NotFound(w, r)
+			return
+		}
+
+		var msg struct {
+			ChatID string `json:"chat_id"`
+			Text   string `json:"text"`
+		}
+		if !decode(w, r, &msg) || msg.ChatID == "" {
+			http.Error(w, `{"ok":false}`, http.StatusBadRequest)
+			return
+		}
+		reply(w, "telegram "+msg.ChatID, msg.Text)
+	})
+
+	log.Printf("notify stand-in listening on :%s", *port)
+	log.Fatal(http.ListenAndServe(":"+*port, nil))
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	return json.NewDecoder(r.Body).Decode(v) == nil
+}
+
+func reply(w http.ResponseWriter, channel, text string) {
+	n := atomic.AddInt64(&count, 1)
+	if failEvery > 0 && n%int64(failEvery) == 0 {
+		fmt.Printf("--- #%d %s %s (answered 500)\n%s\n", n, channel, time.Now().UTC().Format(time.RFC3339), text)
+		http.Error(w, "internal_error", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("--- #%d %s %s\n%s\n", n, channel, time.Now().UTC().Format(time.RFC3339), text)
+	_, _ = w.Write([]byte("ok"))
+}
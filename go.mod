@@ -1,6 +1,6 @@
 module bwd
 
-go 1.13
+go 1.16
 
 require (
 	github.com/adshao/go-binance/v2 v2.2.0
@@ -3,6 +3,7 @@ package app
 import (
 	"bwd/pkg/compound"
 	"bwd/pkg/connector"
+	"bwd/pkg/event"
 	"bwd/pkg/step"
 	"bwd/pkg/storage"
 	"bwd/pkg/trader"
@@ -11,7 +12,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"runtime/debug"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -20,9 +23,11 @@ import (
 )
 
 const (
-	stepperTypeFixInterval      = "FIX_INTERVAL"
-	compounderTypeNone          = "NONE"
-	compounderTypeProfitPercent = "PROFIT_PERCENT"
+	stepperTypeFixInterval          = "FIX_INTERVAL"
+	compounderTypeNone              = "NONE"
+	compounderTypeProfitPercent     = "PROFIT_PERCENT"
+	compounderTypeBaseProfitPercent = "BASE_PROFIT_PERCENT"
+	compounderTypeDistanceWeighted  = "DISTANCE_WEIGHTED"
 )
 
 var (
@@ -49,6 +54,15 @@ type ConfigApp struct {
 	CompoundType       string
 	CompoundDetails    string
 	PublishOrderNumber int
+	Direction          string
+	SkimPercent        float64
+	QuoteBudget        float64
+	QuotePercentUse    float64
+	StopPolicy         string
+	// WindingDown starts app without opening new trades
+	WindingDown bool
+	// Events receives trade domain events, optional
+	Events event.Publisher
 }
 
 type App struct {
@@ -56,6 +70,7 @@ type App struct {
 	logger             logrus.FieldLogger
 	storer             storage.Storer
 	connector          connector.Connector
+	events             event.Publisher
 	interval           time.Duration
 	id                 int
 	exchange           string
@@ -66,6 +81,16 @@ type App struct {
 	compound           compoundSettings
 	pairInfo           pairInfo
 	publishOrderNumber int
+	direction          string
+	skimPercent        float64
+	quoteBudget        float64
+	quotePercentUse    float64
+	windingDown        bool
+	stopPolicy         string
+	liveM              sync.Mutex
+	pendingLive        *LiveConfig
+	crashM             sync.Mutex
+	crashErr           error
 	doneSig            chan struct{}
 	stepQuoteVolume    float64
 	cancelFunc         func()
@@ -74,6 +99,13 @@ type App struct {
 	trader             *trader.Trader
 }
 
+// LiveConfig contains app settings that can be changed while app is running
+type LiveConfig struct {
+	Interval        time.Duration
+	MarketOrderFees float64
+	LimitOrderFees  float64
+}
+
 type pair struct {
 	base, quote string
 }
@@ -111,6 +143,7 @@ func New(cfg *ConfigApp, logger logrus.FieldLogger) *App {
 		logger:     logger.WithField("module", "app").WithField("appid", cfg.ID),
 		storer:     cfg.Storer,
 		connector:  cfg.Connector,
+		events:     cfg.Events,
 		interval:   cfg.Interval,
 		id:         cfg.ID,
 		exchange:   cfg.Exchange,
@@ -136,6 +169,12 @@ func New(cfg *ConfigApp, logger logrus.FieldLogger) *App {
 		},
 		stepQuoteVolume:    cfg.StepQuoteVolume,
 		publishOrderNumber: cfg.PublishOrderNumber,
+		direction:          cfg.Direction,
+		skimPercent:        cfg.SkimPercent,
+		quoteBudget:        cfg.QuoteBudget,
+		quotePercentUse:    cfg.QuotePercentUse,
+		windingDown:        cfg.WindingDown,
+		stopPolicy:         cfg.StopPolicy,
 		doneSig:            make(chan struct{}),
 	}
 }
@@ -145,26 +184,33 @@ func New(cfg *ConfigApp, logger logrus.FieldLogger) *App {
 // validate app if it is properly configured
 // init trader dependencies (stepper, compounder)
 // init trader and run it on loop (at tick interval)
-func (a *App) Start() error {
-	if err := a.exchangePairInfo(); err != nil {
-		return fmt.Errorf("fail exchangePairInfo, err: %w", err)
-	}
-
-	if err := a.validate(); err != nil {
-		return fmt.Errorf("fail validate, err: %w", err)
-	}
+// a panic during start is returned as error
+func (a *App) Start() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("start panic: %v", r)
+			metricError.With(prometheus.Labels{"appid": strconv.Itoa(a.id)}).Inc()
+			a.logger.WithField("stack", string(debug.Stack())).WithError(err).Error("app start panic")
+		}
 
-	if err := a.initStepper(); err != nil {
-		return fmt.Errorf("fail initStepper, err: %w", err)
-	}
+		// app loop never started, nothing else releases its context
+		if err != nil {
+			a.Close()
+		}
+	}()
 
-	if err := a.initCompounder(); err != nil {
-		return fmt.Errorf("fail initCompounder, err: %w", err)
+	if err := a.Validate(); err != nil {
+		return err
 	}
 
 	a.initTrader()
 
+	if a.windingDown {
+		a.trader.WindDown()
+	}
+
 	go func() {
+		var isDone, isCrashed bool
 		for {
 			a.logger.Debug("run app")
 
@@ -173,8 +219,18 @@ func (a *App) Start() error {
 				a.doneSig <- struct{}{}
 				return
 			default:
-				metricRun.With(prometheus.Labels{"appid": strconv.Itoa(a.id)}).Inc()
-				a.trader.Run()
+				a.applyPendingLiveConfig()
+
+				// wound down or crashed app waits to be stopped
+				if !isDone && !isCrashed {
+					metricRun.With(prometheus.Labels{"appid": strconv.Itoa(a.id)}).Inc()
+					if err := a.runTrader(); err != nil {
+						isCrashed = true
+						a.setCrashErr(err)
+					} else {
+						isDone = a.markDoneIfWoundDown()
+					}
+				}
 				<-time.After(a.interval)
 			}
 		}
@@ -183,12 +239,170 @@ func (a *App) Start() error {
 	return nil
 }
 
-// Stop will wait for trader to finish
-func (a *App) Stop() {
+// Validate checks app config against exchange pair info, steps and compounder
+// are initialised but app is not started
+func (a *App) Validate() error {
+	if err := a.exchangePairInfo(); err != nil {
+		return fmt.Errorf("fail exchangePairInfo, err: %w", err)
+	}
+
+	if err := a.validate(); err != nil {
+		return fmt.Errorf("fail validate, err: %w", err)
+	}
+
+	if err := a.initStepper(); err != nil {
+		return fmt.Errorf("fail initStepper, err: %w", err)
+	}
+
+	if violations := a.stepper.Validate(); len(violations) > 0 {
+		return fmt.Errorf("fail stepper validate, err: %w", violations)
+	}
+
+	if err := a.initCompounder(); err != nil {
+		return fmt.Errorf("fail initCompounder, err: %w", err)
+	}
+
+	return nil
+}
+
+// runTrader isolates trader panics, so they do not kill other apps
+func (a *App) runTrader() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("trader run panic: %v", r)
+			metricError.With(prometheus.Labels{"appid": strconv.Itoa(a.id)}).Inc()
+			a.logger.WithField("stack", string(debug.Stack())).WithError(err).Error("app crashed")
+		}
+	}()
+
+	a.trader.Run()
+
+	return nil
+}
+
+// CrashErr returns the panic that stopped app trading, nil while app is healthy
+func (a *App) CrashErr() error {
+	a.crashM.Lock()
+	defer a.crashM.Unlock()
+
+	return a.crashErr
+}
+
+func (a *App) setCrashErr(err error) {
+	a.crashM.Lock()
+	defer a.crashM.Unlock()
+
+	a.crashErr = err
+}
+
+// Reconfigure validates live settings and schedules them to be applied before next trader run
+func (a *App) Reconfigure(cfg LiveConfig) error {
+	if cfg.Interval <= 0 {
+		return errors.New("interval should be greater than 0")
+	}
+
+	if cfg.MarketOrderFees < 0 {
+		return errors.New("market order fee can not be less than 0")
+	}
+
+	if cfg.LimitOrderFees < 0 {
+		return errors.New("limit order fee can not be less than 0")
+	}
+
+	a.liveM.Lock()
+	a.pendingLive = &cfg
+	a.liveM.Unlock()
+
+	return nil
+}
+
+// applyPendingLiveConfig runs on app goroutine, between trader runs
+func (a *App) applyPendingLiveConfig() {
+	a.liveM.Lock()
+	cfg := a.pendingLive
+	a.pendingLive = nil
+	a.liveM.Unlock()
+
+	if cfg == nil {
+		return
+	}
+
+	a.interval = cfg.Interval
+	a.fees.market = cfg.MarketOrderFees
+	a.fees.limit = cfg.LimitOrderFees
+	a.trader.SetFees(cfg.MarketOrderFees, cfg.LimitOrderFees)
+
+	a.logger.WithField("datalive", fmt.Sprintf("%+v", *cfg)).Info("live config applied")
+}
+
+// WindDown stops app from opening new trades, app is marked as done
+// in storage when all trades are closed
+func (a *App) WindDown() {
+	if a.trader.IsWindingDown() {
+		return
+	}
+
+	a.logger.Info("app winding down")
+	a.trader.WindDown()
+}
+
+// Steps returns grid levels of started app
+func (a *App) Steps() []float64 {
+	return a.stepper.Steps()
+}
+
+func (a *App) IsWindingDown() bool {
+	return a.trader.IsWindingDown()
+}
+
+func (a *App) markDoneIfWoundDown() bool {
+	if !a.trader.IsWoundDown() {
+		return false
+	}
+
+	if err := a.storer.MarkAppDone(a.id); err != nil {
+		metricError.With(prometheus.Labels{"appid": strconv.Itoa(a.id)}).Inc()
+		a.logger.WithError(err).Error("fail mark app done")
+		return false
+	}
+
+	a.logger.Info("app wound down, marked as done")
+
+	return true
+}
+
+// Close releases app context of an app that is not started, like one only validated
+func (a *App) Close() {
+	a.cancelFunc()
+}
+
+// Pause will wait for trader to finish, exchange orders are left live
+func (a *App) Pause() {
 	a.cancelFunc()
 	<-a.doneSig
 }
 
+// Stop will wait for trader to finish and apply stop policy on exchange orders
+func (a *App) Stop() {
+	a.Pause()
+
+	var sides []string
+	switch a.stopPolicy {
+	case trader.StopPolicyCancelBuys:
+		sides = []string{connector.OrderSideBuy}
+	case trader.StopPolicyCancelAll:
+		sides = []string{connector.OrderSideBuy, connector.OrderSideSell}
+	default:
+		return
+	}
+
+	a.logger.WithField("stoppolicy", a.stopPolicy).Info("cancel orders on app stop")
+	if ok := a.trader.UnpublishOrders(sides); !ok {
+		metricError.With(prometheus.Labels{"appid": strconv.Itoa(a.id)}).Inc()
+		a.logger.Error("fail cancel orders on app stop")
+	}
+}
+
 func (a *App) validate() error {
 	if a.id < 1 {
 		return errors.New("appId should be unique and greater than 1")
@@ -234,6 +448,47 @@ func (a *App) validate() error {
 		return errors.New("publishOrdersNumber should be at least 1")
 	}
 
+	switch a.direction {
+	case trader.DirectionBuySell:
+		if a.compound.kind == compounderTypeBaseProfitPercent {
+			return fmt.Errorf("compoundType %s can not be used on %s grid", a.compound.kind, a.direction)
+		}
+	case trader.DirectionSellBuy:
+		if a.compound.kind == compounderTypeProfitPercent {
+			return fmt.Errorf("compoundType %s can not be used on %s grid", a.compound.kind, a.direction)
+		}
+	default:
+		return fmt.Errorf("unknown direction: %s", a.direction)
+	}
+
+	if a.skimPercent < 0 || a.skimPercent > 100 {
+		return errors.New("skimPercent should be in range 0 - 100")
+	}
+
+	// only quote profit can be reserved for withdraw
+	if a.skimPercent > 0 && a.direction == trader.DirectionSellBuy {
+		return fmt.Errorf("skimPercent can not be used on %s grid", a.direction)
+	}
+
+	switch a.stopPolicy {
+	case trader.StopPolicyLeave, trader.StopPolicyCancelBuys, trader.StopPolicyCancelAll:
+	default:
+		return fmt.Errorf("unknown stopPolicy: %s", a.stopPolicy)
+	}
+
+	if a.quoteBudget < 0 {
+		return errors.New("quoteBudget can not be less than 0")
+	}
+
+	if a.quotePercentUse < 0 || a.quotePercentUse > 100 {
+		return errors.New("quotePercentUse should be in range 0 - 100")
+	}
+
+	// capital budget is expressed in quote locked by buys
+	if (a.quoteBudget > 0 || a.quotePercentUse > 0) && a.direction == trader.DirectionSellBuy {
+		return fmt.Errorf("quoteBudget and quotePercentUse can not be used on %s grid", a.direction)
+	}
+
 	return nil
 }
 
@@ -273,6 +528,12 @@ func (a *App) initStepper() error {
 			MinPriceAllowed: a.pairInfo.basePrice.min,
 			MaxPriceAllowed: a.pairInfo.basePrice.max,
 			PriceTick:       a.pairInfo.basePrice.tick,
+			QuoteMinVolume:  a.pairInfo.quoteMinVolume,
+			BaseLotMin:      a.pairInfo.baseLot.min,
+			BaseLotMax:      a.pairInfo.baseLot.max,
+			BaseLotTick:     a.pairInfo.baseLot.tick,
+			StepQuoteVolume: a.stepQuoteVolume,
+			Inverse:         a.direction == trader.DirectionSellBuy,
 			AppSettings: fmt.Sprintf(`{"min":"%s","max":"%s","interval":"%s"}`,
 				strconv.FormatFloat(a.basePrice.min, 'f', -1, 64),
 				strconv.FormatFloat(a.basePrice.max, 'f', -1, 64),
@@ -306,6 +567,19 @@ func (a *App) initCompounder() error {
 			BaseLotTick:            a.pairInfo.baseLot.tick,
 		})
 	case compounderTypeProfitPercent:
+		details, err := parseProfitPercentDetails(a.compound.details)
+		if err != nil {
+			return fmt.Errorf("invalid compound details: %s for compounder type: %s, err: %w",
+				a.compound.details,
+				compounderTypeProfitPercent,
+				err,
+			)
+		}
+
+		if details.maxStepQuoteVolume > 0 && details.maxStepQuoteVolume < a.stepQuoteVolume {
+			return errors.New("compound details maxStepQuoteVolume can not be less than stepQuoteVolume")
+		}
+
 		a.compounder = compound.NewProfitPercent(&compound.ConfigProfitPercent{
 			AppID:                  a.id,
 			Storer:                 a.storer,
@@ -313,13 +587,176 @@ func (a *App) initCompounder() error {
 			MinBaseLotAllowed:      a.pairInfo.baseLot.min,
 			MaxBaseLotAllowed:      a.pairInfo.baseLot.max,
 			BaseLotTick:            a.pairInfo.baseLot.tick,
+			ReinvestPercent:        details.reinvestPercent,
+			MaxReinvestQuoteVolume: details.maxReinvestQuoteVolume,
+			MaxStepQuoteVolume:     details.maxStepQuoteVolume,
+		})
+	case compounderTypeBaseProfitPercent:
+		a.compounder = compound.NewBaseProfitPercent(&compound.ConfigBaseProfitPercent{
+			AppID:                  a.id,
+			Storer:                 a.storer,
+			InitialStepQuoteVolume: a.stepQuoteVolume,
+			MinBaseLotAllowed:      a.pairInfo.baseLot.min,
+			MaxBaseLotAllowed:      a.pairInfo.baseLot.max,
+			BaseLotTick:            a.pairInfo.baseLot.tick,
+		})
+	case compounderTypeDistanceWeighted:
+		details, err := parseDistanceWeightedDetails(a.compound.details)
+		if err != nil {
+			return fmt.Errorf("invalid compound details: %s for compounder type: %s, err: %w",
+				a.compound.details,
+				compounderTypeDistanceWeighted,
+				err,
+			)
+		}
+
+		c, err := compound.NewDistanceWeighted(&compound.ConfigDistanceWeighted{
+			InitialStepQuoteVolume: a.stepQuoteVolume,
+			MinBaseLotAllowed:      a.pairInfo.baseLot.min,
+			MaxBaseLotAllowed:      a.pairInfo.baseLot.max,
+			BaseLotTick:            a.pairInfo.baseLot.tick,
+			QuoteMinVolume:         a.pairInfo.quoteMinVolume,
+			Steps:                  a.stepper.Steps(),
+			Inverse:                a.direction == trader.DirectionSellBuy,
+			Mode:                   details.mode,
+			Factor:                 details.factor,
+			MaxQuoteCapital:        details.maxQuoteCapital,
 		})
+		if err != nil {
+			return fmt.Errorf("could not init compounder: %s, err: %w", compounderTypeDistanceWeighted, err)
+		}
+
+		a.compounder = c
 	default:
 		return fmt.Errorf("unknown compounder type: %s", a.compound.kind)
 	}
 	return nil
 }
 
+type profitPercentDetails struct {
+	reinvestPercent        float64
+	maxReinvestQuoteVolume float64
+	maxStepQuoteVolume     float64
+}
+
+// parseProfitPercentDetails parses compound details json, ex:
+// {"percent":"50","maxReinvest":"10","maxStepVolume":"200"}
+// empty details or missing keys will reinvest all profit without limits
+func parseProfitPercentDetails(details string) (profitPercentDetails, error) {
+	d := profitPercentDetails{
+		reinvestPercent: 100,
+	}
+
+	if details == "" {
+		return d, nil
+	}
+
+	tmp := struct {
+		Percent       string `json:"percent"`
+		MaxReinvest   string `json:"maxReinvest"`
+		MaxStepVolume string `json:"maxStepVolume"`
+	}{}
+
+	if err := json.Unmarshal([]byte(details), &tmp); err != nil {
+		return d, err
+	}
+
+	if tmp.Percent != "" {
+		percent, err := strconv.ParseFloat(tmp.Percent, 64)
+		if err != nil {
+			return d, err
+		}
+		d.reinvestPercent = percent
+	}
+
+	if tmp.MaxReinvest != "" {
+		maxReinvest, err := strconv.ParseFloat(tmp.MaxReinvest, 64)
+		if err != nil {
+			return d, err
+		}
+		d.maxReinvestQuoteVolume = maxReinvest
+	}
+
+	if tmp.MaxStepVolume != "" {
+		maxStepVolume, err := strconv.ParseFloat(tmp.MaxStepVolume, 64)
+		if err != nil {
+			return d, err
+		}
+		d.maxStepQuoteVolume = maxStepVolume
+	}
+
+	if d.reinvestPercent <= 0 || d.reinvestPercent > 100 {
+		return d, errors.New("percent should be in range (0 - 100]")
+	}
+
+	if d.maxReinvestQuoteVolume < 0 {
+		return d, errors.New("maxReinvest can not be less than 0")
+	}
+
+	if d.maxStepQuoteVolume < 0 {
+		return d, errors.New("maxStepVolume can not be less than 0")
+	}
+
+	return d, nil
+}
+
+type distanceWeightedDetails struct {
+	mode            string
+	factor          float64
+	maxQuoteCapital float64
+}
+
+// parseDistanceWeightedDetails parses compound details json, ex:
+// {"mode":"LINEAR","factor":"0.1","maxCapital":"1000"}
+// maxCapital is optional, missing means no total capital ceiling
+func parseDistanceWeightedDetails(details string) (distanceWeightedDetails, error) {
+	d := distanceWeightedDetails{}
+
+	tmp := struct {
+		Mode       string `json:"mode"`
+		Factor     string `json:"factor"`
+		MaxCapital string `json:"maxCapital"`
+	}{}
+
+	if err := json.Unmarshal([]byte(details), &tmp); err != nil {
+		return d, err
+	}
+	d.mode = tmp.Mode
+
+	factor, err := strconv.ParseFloat(tmp.Factor, 64)
+	if err != nil {
+		return d, err
+	}
+	d.factor = factor
+
+	if tmp.MaxCapital != "" {
+		maxCapital, err := strconv.ParseFloat(tmp.MaxCapital, 64)
+		if err != nil {
+			return d, err
+		}
+		d.maxQuoteCapital = maxCapital
+	}
+
+	switch d.mode {
+	case compound.DistanceWeightedLinear:
+		if d.factor < 0 {
+			return d, errors.New("factor can not be less than 0 for LINEAR mode")
+		}
+	case compound.DistanceWeightedGeometric:
+		if d.factor < 1 {
+			return d, errors.New("factor can not be less than 1 for GEOMETRIC mode")
+		}
+	default:
+		return d, fmt.Errorf("unknown mode: %s", d.mode)
+	}
+
+	if d.maxQuoteCapital < 0 {
+		return d, errors.New("maxCapital can not be less than 0")
+	}
+
+	return d, nil
+}
+
 func (a *App) initTrader() {
 	cfgTrader := &trader.ConfigTrader{
 		AppID:           a.id,
@@ -327,10 +764,18 @@ func (a *App) initTrader() {
 		Quote:           a.pair.quote,
 		MarketOrderFees: a.fees.market,
 		LimitOrderFees:  a.fees.limit,
+		BaseLotTick:     a.pairInfo.baseLot.tick,
+		BaseLotMin:      a.pairInfo.baseLot.min,
+		QuoteMinVolume:  a.pairInfo.quoteMinVolume,
+		QuoteBudget:     a.quoteBudget,
+		QuotePercentUse: a.quotePercentUse,
+		Direction:       a.direction,
+		SkimPercent:     a.skimPercent,
 		Storer:          a.storer,
 		Connector:       a.connector,
 		Stepper:         a.stepper,
 		Compounder:      a.compounder,
+		Events:          a.events,
 	}
 
 	a.trader = trader.New(cfgTrader, a.logger)
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"bwd/pkg/compound"
+	"bwd/pkg/connector"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseProfitPercentDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		details string
+		want    profitPercentDetails
+		wantErr bool
+	}{
+		{
+			name:    "empty details reinvest all",
+			details: "",
+			want:    profitPercentDetails{reinvestPercent: 100},
+		},
+		{
+			name:    "missing keys reinvest all",
+			details: `{}`,
+			want:    profitPercentDetails{reinvestPercent: 100},
+		},
+		{
+			name:    "all keys",
+			details: `{"percent":"50","maxReinvest":"10","maxStepVolume":"200"}`,
+			want:    profitPercentDetails{reinvestPercent: 50, maxReinvestQuoteVolume: 10, maxStepQuoteVolume: 200},
+		},
+		{
+			name:    "invalid json",
+			details: `{"percent":50`,
+			wantErr: true,
+		},
+		{
+			name:    "percent not a number",
+			details: `{"percent":"half"}`,
+			wantErr: true,
+		},
+		{
+			name:    "zero percent",
+			details: `{"percent":"0"}`,
+			wantErr: true,
+		},
+		{
+			name:    "percent over 100",
+			details: `{"percent":"101"}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative max reinvest",
+			details: `{"maxReinvest":"-1"}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative max step volume",
+			details: `{"maxStepVolume":"-1"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProfitPercentDetails(tt.details)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got details: %+v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected details: %+v, got: %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseDistanceWeightedDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		details string
+		want    distanceWeightedDetails
+		wantErr bool
+	}{
+		{
+			name:    "linear with max capital",
+			details: `{"mode":"LINEAR","factor":"0.1","maxCapital":"1000"}`,
+			want:    distanceWeightedDetails{mode: compound.DistanceWeightedLinear, factor: 0.1, maxQuoteCapital: 1000},
+		},
+		{
+			name:    "geometric without max capital",
+			details: `{"mode":"GEOMETRIC","factor":"1.5"}`,
+			want:    distanceWeightedDetails{mode: compound.DistanceWeightedGeometric, factor: 1.5},
+		},
+		{
+			name:    "empty details",
+			details: "",
+			wantErr: true,
+		},
+		{
+			name:    "missing factor",
+			details: `{"mode":"LINEAR"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			details: `{"mode":"CUBIC","factor":"2"}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative linear factor",
+			details: `{"mode":"LINEAR","factor":"-0.1"}`,
+			wantErr: true,
+		},
+		{
+			name:    "geometric factor under 1",
+			details: `{"mode":"GEOMETRIC","factor":"0.9"}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative max capital",
+			details: `{"mode":"LINEAR","factor":"1","maxCapital":"-1"}`,
+			wantErr: true,
+		},
+		{
+			name:    "max capital not a number",
+			details: `{"mode":"LINEAR","factor":"1","maxCapital":"all"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDistanceWeightedDetails(tt.details)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got details: %+v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected details: %+v, got: %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+// fakeConnector fails pair info, methods not overridden panic
+type fakeConnector struct {
+	connector.Connector
+}
+
+func (f *fakeConnector) PairInfo(base, quote string) (connector.PairInfo, error) {
+	return connector.PairInfo{}, errors.New("pair not found")
+}
+
+func TestAppContextReleased(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(a *App)
+	}{
+		{name: "closed after validate", run: func(a *App) { _ = a.Validate(); a.Close() }},
+		{name: "failed start", run: func(a *App) { _ = a.Start() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+
+			a := New(&ConfigApp{ID: 1, Base: "BTC", Quote: "USDT", Connector: &fakeConnector{}}, logger)
+			tt.run(a)
+
+			if a.ctx.Err() == nil {
+				t.Error("expected app context cancelled")
+			}
+		})
+	}
+}
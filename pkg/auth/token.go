@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+const (
+	// RoleReadOnly can call read endpoints
+	RoleReadOnly = "READ_ONLY"
+	// RoleAdmin can call all endpoints
+	RoleAdmin = "ADMIN"
+)
+
+const tokenPrefix = "bwd_"
+
+// ValidRole reports if role is known
+func ValidRole(role string) bool {
+	return role == RoleReadOnly || role == RoleAdmin
+}
+
+// Allows reports if role grants access to a route requiring required role
+func Allows(role, required string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+
+	return role == required
+}
+
+// GenerateToken returns a new random token and its hash, only hash should be stored
+func GenerateToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", "", err
+	}
+
+	token := tokenPrefix + hex.EncodeToString(b)
+
+	return token, HashToken(token), nil
+}
+
+// HashToken returns sha256 hex of token, tokens are random so no salt is needed
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
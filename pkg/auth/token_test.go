@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		required string
+		want     bool
+	}{
+		{name: "admin on admin route", role: RoleAdmin, required: RoleAdmin, want: true},
+		{name: "admin on read route", role: RoleAdmin, required: RoleReadOnly, want: true},
+		{name: "read only on read route", role: RoleReadOnly, required: RoleReadOnly, want: true},
+		{name: "read only on admin route", role: RoleReadOnly, required: RoleAdmin, want: false},
+		{name: "unknown role on read route", role: "GUEST", required: RoleReadOnly, want: false},
+		{name: "empty role on read route", role: "", required: RoleReadOnly, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Allows(tt.role, tt.required); got != tt.want {
+				t.Errorf("expected: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{name: "empty", token: "", want: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{name: "token", token: "bwd_test", want: "1874c88f68bdf5e8a7a4a43a20a84215cc72e2b6382a79b0bc9e2d67e8bcb48b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashToken(tt.token); got != tt.want {
+				t.Errorf("expected: %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGenerateToken(t *testing.T) {
+	token, hash, err := GenerateToken()
+	if err != nil {
+		t.Fatalf("fail generate token, err: %s", err)
+	}
+
+	if !strings.HasPrefix(token, tokenPrefix) {
+		t.Errorf("expected token prefix: %s, got: %s", tokenPrefix, token)
+	}
+	if hash != HashToken(token) {
+		t.Error("expected returned hash to match token hash")
+	}
+
+	other, _, err := GenerateToken()
+	if err != nil {
+		t.Fatalf("fail generate token, err: %s", err)
+	}
+	if other == token {
+		t.Error("expected random tokens to differ")
+	}
+}
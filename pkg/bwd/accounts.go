@@ -0,0 +1,110 @@
+package bwd
+
+import (
+	"bwd/pkg/storage"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// default credentials, used by apps without account
+const defaultCredentialsRef = "BINANCE"
+
+// credentials are api keys of an exchange account
+type credentials struct {
+	apiKey    string
+	secretKey string
+}
+
+// String keeps credentials out of logs and formatted errors
+func (c credentials) String() string {
+	return "[REDACTED]"
+}
+
+// refreshAccounts loads accounts from storage, apps are bound to them by id
+// returns connector keys of accounts whose credentials were rotated
+func (b *Bwd) refreshAccounts() ([]string, error) {
+	accounts, err := b.storer.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var rotated []string
+	refreshed := make(map[int]storage.Account)
+	for _, acc := range accounts {
+		prev, ok := b.accounts[acc.ID]
+		if ok && (prev.APIKeyEnc != acc.APIKeyEnc || prev.SecretKeyEnc != acc.SecretKeyEnc) {
+			rotated = append(rotated, connectorKey(prev))
+		}
+		refreshed[acc.ID] = acc
+	}
+	b.accounts = refreshed
+
+	return rotated, nil
+}
+
+// appAccount returns account used by app, apps without account use
+// default credentials of their exchange
+func appAccount(accounts map[int]storage.Account, appCfg storage.App) (storage.Account, error) {
+	if appCfg.AccountID == 0 {
+		return storage.Account{
+			Exchange:       appCfg.Exchange,
+			CredentialsRef: defaultCredentialsRef,
+		}, nil
+	}
+
+	acc, ok := accounts[appCfg.AccountID]
+	if !ok {
+		return storage.Account{}, fmt.Errorf("unknown account: %d", appCfg.AccountID)
+	}
+
+	if acc.Exchange != appCfg.Exchange {
+		return storage.Account{}, fmt.Errorf("account %d exchange %s does not match app exchange %s", acc.ID, acc.Exchange, appCfg.Exchange)
+	}
+
+	return acc, nil
+}
+
+// connectorKey identifies connector instance of an account, one per account,
+// account id is used as label can be renamed
+func connectorKey(acc storage.Account) string {
+	if acc.ID == 0 {
+		return acc.Exchange
+	}
+
+	return fmt.Sprintf("%s:%d", acc.Exchange, acc.ID)
+}
+
+// accountCredentials decrypts account credentials stored with master key, accounts
+// without stored credentials read them from env vars named by their credentials
+// reference: <REF>_API_KEY and <REF>_SECRET_KEY
+func (b *Bwd) accountCredentials(acc storage.Account) (credentials, error) {
+	if acc.APIKeyEnc != "" || acc.SecretKeyEnc != "" {
+		if b.cipher == nil {
+			return credentials{}, errors.New("master key not configured, can not decrypt account credentials")
+		}
+
+		apiKey, err := b.cipher.Decrypt(acc.APIKeyEnc)
+		if err != nil {
+			return credentials{}, fmt.Errorf("account %d api key, err: %w", acc.ID, err)
+		}
+
+		secretKey, err := b.cipher.Decrypt(acc.SecretKeyEnc)
+		if err != nil {
+			return credentials{}, fmt.Errorf("account %d secret key, err: %w", acc.ID, err)
+		}
+
+		return credentials{apiKey: apiKey, secretKey: secretKey}, nil
+	}
+
+	ref := strings.ToUpper(acc.CredentialsRef)
+	if ref == "" {
+		return credentials{}, fmt.Errorf("account %d has no credentials reference", acc.ID)
+	}
+
+	return credentials{
+		apiKey:    os.Getenv(ref + "_API_KEY"),
+		secretKey: os.Getenv(ref + "_SECRET_KEY"),
+	}, nil
+}
@@ -0,0 +1,95 @@
+package bwd
+
+import (
+	"bwd/pkg/storage"
+	"bwd/pkg/utils/metrics/exporter"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// maxStartBackoff is the longest delay between two start attempts of a failing app
+	maxStartBackoff = 10 * time.Minute
+)
+
+var (
+	metricAppStartFailure  = exporter.GetCounter("bwd", "app_start_failure_count", []string{"appid"})
+	metricAppStartAttempts = exporter.GetGauge("bwd", "app_start_attempts", []string{"appid"})
+)
+
+// startBackoff keeps failed start attempts of an app
+type startBackoff struct {
+	attempts      int
+	nextAttemptAt time.Time
+	configHash    string
+	// set when app started after failures, a crash soon after start keeps increasing delay
+	startedAt time.Time
+}
+
+// canStartApp returns false while app waits for backoff delay
+// a config change allows an immediate retry
+func (b *Bwd) canStartApp(appCfg storage.App) bool {
+	backoff, ok := b.startBackoffs[appCfg.ID]
+	if !ok {
+		return true
+	}
+
+	if backoff.configHash != appConfigHash(appCfg) {
+		return true
+	}
+
+	return !time.Now().Before(backoff.nextAttemptAt)
+}
+
+// registerStartFailure doubles delay until next start attempt, starting from bwd interval
+func (b *Bwd) registerStartFailure(appCfg storage.App) {
+	backoff, ok := b.startBackoffs[appCfg.ID]
+	if !ok {
+		backoff = &startBackoff{}
+		b.startBackoffs[appCfg.ID] = backoff
+	}
+
+	// app was healthy long enough, start counting again
+	if !backoff.startedAt.IsZero() && time.Since(backoff.startedAt) > maxStartBackoff {
+		backoff.attempts = 0
+	}
+	backoff.startedAt = time.Time{}
+
+	backoff.attempts++
+	backoff.configHash = appConfigHash(appCfg)
+
+	delay := b.interval
+	for i := 1; i < backoff.attempts && delay < maxStartBackoff; i++ {
+		delay = delay * 2
+	}
+	if delay > maxStartBackoff {
+		delay = maxStartBackoff
+	}
+	backoff.nextAttemptAt = time.Now().Add(delay)
+
+	labels := prometheus.Labels{"appid": strconv.Itoa(appCfg.ID)}
+	metricAppStartFailure.With(labels).Inc()
+	metricAppStartAttempts.With(labels).Set(float64(backoff.attempts))
+}
+
+// markStarted keeps failed attempts of a started app until it proves healthy
+func (b *Bwd) markStarted(appID int) {
+	if backoff, ok := b.startBackoffs[appID]; ok {
+		backoff.startedAt = time.Now()
+	}
+	metricAppStartAttempts.With(prometheus.Labels{"appid": strconv.Itoa(appID)}).Set(0)
+}
+
+func (b *Bwd) resetStartBackoff(appID int) {
+	delete(b.startBackoffs, appID)
+	metricAppStartAttempts.With(prometheus.Labels{"appid": strconv.Itoa(appID)}).Set(0)
+}
+
+func (b *Bwd) startAttempts(appID int) int {
+	if backoff, ok := b.startBackoffs[appID]; ok && backoff.startedAt.IsZero() {
+		return backoff.attempts
+	}
+	return 0
+}
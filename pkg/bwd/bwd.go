@@ -3,11 +3,16 @@ package bwd
 import (
 	"bwd/pkg/app"
 	"bwd/pkg/connector"
+	"bwd/pkg/event"
+	"bwd/pkg/notify"
+	"bwd/pkg/secret"
 	"bwd/pkg/storage"
+	"bwd/pkg/web"
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -18,11 +23,31 @@ const (
 	fakeConnector    = "FAKE"
 )
 
+const (
+	appStatusActive      = "ACTIVE"
+	appStatusInactive    = "INACTIVE"
+	appStatusWindingDown = "WINDING_DOWN"
+	appStatusPaused      = "PAUSED"
+)
+
+const (
+	runtimeStatusRunning = "RUNNING"
+	runtimeStatusFailed  = "FAILED"
+	runtimeStatusStopped = "STOPPED"
+	runtimeStatusCrashed = "CRASHED"
+)
+
 type ConfigBwd struct {
-	Interval                time.Duration
-	SlackHook               string
+	Interval  time.Duration
+	SlackHook string
+	// NotifyConfig is a json file with notification channels, optional
+	NotifyConfig string
+	// DailyReportTime is HH:MM UTC when daily report is generated, empty disables it
+	DailyReportTime         string
 	WebBindingPort          string
 	StorageConnectionString string
+	// MasterKey decrypts exchange credentials stored in accounts, optional
+	MasterKey []byte
 }
 
 type Bwd struct {
@@ -31,25 +56,70 @@ type Bwd struct {
 	storer                  storage.Storer
 	interval                time.Duration
 	slackHook               string
+	notifyConfig            string
+	dailyReportTime         string
 	webBindingPort          string
 	storageConnectionString string
+	masterKey               []byte
+	cipher                  *secret.Cipher
 	connectors              map[string]connector.Connector
-	runningApps             map[int]*app.App
+	accounts                map[int]storage.Account
+	appConnectors           map[int]string
+	runningApps             map[int]runningApp
+	newApp                  func(appCfg storage.App, c connector.Connector) runningApp
+	appConfigs              map[int]storage.App
+	startBackoffs           map[int]*startBackoff
+	stateMu                 sync.RWMutex
+	state                   state
+	web                     *web.Server
+	events                  *event.Bus
+	notifier                *notify.Router
+	notifyEvents            *event.Subscription
+	reportEnabled           bool
+	reportAt                time.Duration
+	reportLastEnd           time.Time
 	isDone                  chan struct{}
 }
 
+// runningApp is an app started by bwd
+type runningApp interface {
+	Start() error
+	// Pause stops trader, exchange orders stay live
+	Pause()
+	// Stop stops trader and applies app stop policy on exchange orders
+	Stop()
+	WindDown()
+	IsWindingDown() bool
+	CrashErr() error
+	Reconfigure(cfg app.LiveConfig) error
+	Steps() []float64
+}
+
 func New(ctx context.Context, cfg *ConfigBwd, logger logrus.FieldLogger) *Bwd {
-	return &Bwd{
+	b := &Bwd{
 		ctx:                     ctx,
 		logger:                  logger.WithField("module", "bwd"),
 		interval:                cfg.Interval,
 		slackHook:               cfg.SlackHook,
+		notifyConfig:            cfg.NotifyConfig,
+		dailyReportTime:         cfg.DailyReportTime,
 		webBindingPort:          cfg.WebBindingPort,
 		storageConnectionString: cfg.StorageConnectionString,
+		masterKey:               cfg.MasterKey,
 		connectors:              make(map[string]connector.Connector),
-		runningApps:             make(map[int]*app.App),
+		accounts:                make(map[int]storage.Account),
+		appConnectors:           make(map[int]string),
+		runningApps:             make(map[int]runningApp),
+		appConfigs:              make(map[int]storage.App),
+		startBackoffs:           make(map[int]*startBackoff),
+		events:                  event.NewBus(),
 		isDone:                  make(chan struct{}),
 	}
+	b.newApp = func(appCfg storage.App, c connector.Connector) runningApp {
+		return b.createApp(appCfg, c)
+	}
+
+	return b
 }
 
 func (b *Bwd) Start() error {
@@ -61,26 +131,68 @@ func (b *Bwd) Start() error {
 
 	b.storer = sql
 
+	if len(b.masterKey) > 0 {
+		c, err := secret.New(b.masterKey)
+		if err != nil {
+			return fmt.Errorf("bwd create credentials cipher fail, err: %w", err)
+		}
+		b.cipher = c
+	}
+
+	if err := b.initDailyReport(); err != nil {
+		return fmt.Errorf("bwd init daily report fail, err: %w", err)
+	}
+
+	if err := b.startNotifier(); err != nil {
+		return fmt.Errorf("bwd start notifier fail, err: %w", err)
+	}
+
+	// web api is optional
+	if b.webBindingPort != "" {
+		b.web = web.New(&web.ConfigServer{
+			Port:   b.webBindingPort,
+			Storer: b.storer,
+			State:  b,
+			Events: b.events,
+		}, b.logger)
+
+		if err := b.web.Start(); err != nil {
+			return fmt.Errorf("bwd start web server fail, err: %w", err)
+		}
+	}
+
 	// start goroutine that will create runningApps instances and update them
 	// with new parameters periodically
 	go func() {
 		for {
 			select {
 			case <-b.ctx.Done():
+				if b.web != nil {
+					b.web.Stop()
+				}
+
+				b.notify(notify.KindAppStopped, 0, "bwd shutdown, %d running apps stopped", len(b.runningApps))
+
 				// stop apps
-				for _, a := range b.runningApps {
+				for appID, a := range b.runningApps {
 					a.Stop()
+					runtime := storage.AppRuntime{Status: runtimeStatusStopped}
+					if err := b.storer.UpdateAppRuntime(appID, runtime); err != nil {
+						b.logger.WithError(err).WithField("appid", appID).Error("fail update app runtime status")
+					}
 				}
 
 				// stop connectors
-				for _, c := range b.connectors {
-					c.Stop()
+				for key, c := range b.connectors {
+					b.stopConnector(key, c)
 				}
 
+				b.stopNotifier()
+
 				close(b.isDone)
 				return
 			default:
-				b.run()
+				b.safeRun()
 				<-time.After(b.interval)
 			}
 		}
@@ -93,6 +205,17 @@ func (b *Bwd) Wait() {
 	<-b.isDone
 }
 
+// safeRun isolates orchestrator panics, apps keep running and next tick retries
+func (b *Bwd) safeRun() {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.WithField("stack", string(debug.Stack())).Errorf("bwd run panic: %v", r)
+		}
+	}()
+
+	b.run()
+}
+
 func (b *Bwd) run() {
 	b.logger.Debug("run bwd")
 	apps, err := b.storer.Apps()
@@ -101,40 +224,72 @@ func (b *Bwd) run() {
 		return
 	}
 
+	rotated, err := b.refreshAccounts()
+	if err != nil {
+		b.logger.WithError(err).Errorf("fail fetch accounts from storage")
+		return
+	}
+
+	b.handleCrashedApps(apps)
+	b.superviseConnectors(apps)
+
+	// rotated credentials are used by a new connector instance
+	for _, key := range rotated {
+		b.restartConnector(key, apps, fmt.Errorf("connector %s credentials rotated", key))
+	}
+	b.stopUnusedConnectors(apps)
+
 	for _, a := range apps {
+		acc, err := appAccount(b.accounts, a)
+		if err != nil {
+			b.logger.WithError(err).WithField("appid", a.ID).Error("fail resolve app account")
+			b.setRuntimeStatus(a, runtimeStatusFailed, err)
+			continue
+		}
+		key := connectorKey(acc)
+
 		// create connector if not exists
-		_, ok := b.connectors[a.Exchange]
+		_, ok := b.connectors[key]
 		if !ok {
-			b.logger.WithField("dataconnector", a.Exchange).Info("try create connector")
-			c, err := b.createConnector(a.Exchange)
+			b.logger.WithField("dataconnector", key).Info("try create connector")
+			c, err := b.createConnector(acc)
 			if err != nil {
-				b.logger.WithError(err).Errorf("fail to init connector: %s", a.Exchange)
+				b.logger.WithError(err).Errorf("fail to init connector: %s", key)
+				b.setRuntimeStatus(a, runtimeStatusFailed, err)
 				continue
 			}
-			b.logger.WithField("dataconnector", a.Exchange).Info("success connector created")
+			b.logger.WithField("dataconnector", key).Info("success connector created")
 
 			if err = c.Start(); err != nil {
-				b.logger.WithError(err).Error("fail to start connector: %s", a.Exchange)
+				b.logger.WithError(err).Errorf("fail to start connector: %s", key)
+				b.setRuntimeStatus(a, runtimeStatusFailed, fmt.Errorf("fail to start connector: %s, err: %w", key, err))
 				continue
 			}
-			b.logger.WithField("dataconnector", a.Exchange).Info("success connector start")
-			b.connectors[a.Exchange] = c
+			b.logger.WithField("dataconnector", key).Info("success connector start")
+			b.connectors[key] = c
 		}
 
+		b.appConnectors[a.ID] = key
 		b.applyAppConfig(a)
 	}
+
+	b.stopRemovedApps(apps)
+	b.publishState(apps)
+	b.dailyReport(apps)
 }
 
-func (b *Bwd) createConnector(exchange string) (connector.Connector, error) {
-	switch exchange {
+func (b *Bwd) createConnector(acc storage.Account) (connector.Connector, error) {
+	switch acc.Exchange {
 	case binanceConnector:
-		apyKey := os.Getenv("BINANCE_API_KEY")
-		secretKey := os.Getenv("BINANCE_SECRET_KEY")
+		creds, err := b.accountCredentials(acc)
+		if err != nil {
+			return nil, err
+		}
 		binanceCfg := &connector.BinanceConfig{
 			// TODO set interval as config
 			Interval:  3 * time.Second,
-			ApiKey:    apyKey,
-			SecretKey: secretKey,
+			ApiKey:    creds.apiKey,
+			SecretKey: creds.secretKey,
 		}
 		return connector.NewBinance(binanceCfg, b.logger), nil
 	case fakeConnector:
@@ -143,7 +298,7 @@ func (b *Bwd) createConnector(exchange string) (connector.Connector, error) {
 		}
 		return connector.NewFakeConnector(fakeConnectorCfg, b.logger), nil
 	default:
-		return nil, fmt.Errorf("unknown exchange: %s", exchange)
+		return nil, fmt.Errorf("unknown exchange: %s", acc.Exchange)
 	}
 }
 
@@ -151,35 +306,214 @@ func (b *Bwd) applyAppConfig(appCfg storage.App) {
 	appJson, _ := json.Marshal(appCfg)
 	logger := b.logger.WithField("dataapp", string(appJson))
 
+	// is_done belongs to a finished wind down, a status changed directly in
+	// storage does not reset it and next wind down would stop app at once
+	if appCfg.IsDone && appCfg.Status != appStatusWindingDown {
+		if err := b.storer.ClearAppDone(appCfg.ID); err != nil {
+			logger.WithError(err).Error("fail clear app done")
+		} else {
+			appCfg.IsDone = false
+		}
+	}
+
 	switch appCfg.Status {
-	case "ACTIVE":
-		// start app only if not exists in running apps
-		if _, ok := b.runningApps[appCfg.ID]; !ok {
-			logger.Info("try start app")
-			a := b.createApp(appCfg)
-			if err := a.Start(); err != nil {
-				logger.WithError(err).Error("fail start app")
+	case appStatusActive:
+		if a, ok := b.runningApps[appCfg.ID]; ok {
+			// app was winding down, restart it to open trades again, orders
+			// are kept live and reconciled by restarted trader
+			if a.IsWindingDown() {
+				logger.Info("try pause winding down app to resume it")
+				b.stopApp(appCfg.ID, true)
+			} else {
+				// running app only applies config changes
+				b.reconfigureApp(a, appCfg, logger)
 				return
 			}
-			b.runningApps[appCfg.ID] = a
-			logger.Info("success start app")
 		}
-	case "INACTIVE":
-		if a, ok := b.runningApps[appCfg.ID]; ok {
+
+		if !b.canStartApp(appCfg) {
+			return
+		}
+
+		logger.Info("try start app")
+		if err := b.startApp(appCfg); err != nil {
+			logger.WithError(err).Error("fail start app")
+			return
+		}
+		logger.Info("success start app")
+	case appStatusWindingDown:
+		a, ok := b.runningApps[appCfg.ID]
+
+		// all trades closed, app stops automatically
+		if appCfg.IsDone {
+			if ok {
+				logger.Info("try stop wound down app")
+				b.stopApp(appCfg.ID, false)
+				logger.Info("success stop wound down app")
+			}
+			b.setRuntimeStatus(appCfg, runtimeStatusStopped, nil)
+			return
+		}
+
+		if ok {
+			a.WindDown()
+			return
+		}
+
+		if !b.canStartApp(appCfg) {
+			return
+		}
+
+		logger.Info("try start winding down app")
+		if err := b.startApp(appCfg); err != nil {
+			logger.WithError(err).Error("fail start winding down app")
+			return
+		}
+		logger.Info("success start winding down app")
+	case appStatusPaused:
+		if _, ok := b.runningApps[appCfg.ID]; ok {
+			logger.Info("try pause app")
+			b.stopApp(appCfg.ID, true)
+			logger.Info("success pause app")
+		}
+		b.setRuntimeStatus(appCfg, runtimeStatusStopped, nil)
+	case appStatusInactive:
+		if _, ok := b.runningApps[appCfg.ID]; ok {
 			logger.Info("try stop app")
-			a.Stop()
-			delete(b.runningApps, appCfg.ID)
+			b.stopApp(appCfg.ID, false)
 			logger.Info("success stop app")
 		}
+		b.resetStartBackoff(appCfg.ID)
+		b.setRuntimeStatus(appCfg, runtimeStatusStopped, nil)
 	default:
-		logger.Errorf("unknown app status")
+		err := fmt.Errorf("unknown app status: %s", appCfg.Status)
+		// do not keep trading with a config we do not understand
+		if _, ok := b.runningApps[appCfg.ID]; ok {
+			b.stopApp(appCfg.ID, true)
+		}
+		logger.WithError(err).Error("fail apply app config")
+		b.setRuntimeStatus(appCfg, runtimeStatusFailed, err)
+	}
+}
+
+// startApp creates and starts app, runtime status is stored in both cases
+// failed starts are retried with exponential backoff
+func (b *Bwd) startApp(appCfg storage.App) error {
+	a := b.newApp(appCfg, b.connectors[b.appConnectors[appCfg.ID]])
+	if err := a.Start(); err != nil {
+		b.registerStartFailure(appCfg)
+		b.setRuntimeStatus(appCfg, runtimeStatusFailed, err)
+		b.notify(notify.KindAppFailed, appCfg.ID, "app %d %s/%s fail start, attempt %d: %s",
+			appCfg.ID, appCfg.Base, appCfg.Quote, b.startAttempts(appCfg.ID), err.Error())
+		return err
+	}
+
+	b.markStarted(appCfg.ID)
+	b.runningApps[appCfg.ID] = a
+	b.appConfigs[appCfg.ID] = appCfg
+	b.setRuntimeStatus(appCfg, runtimeStatusRunning, nil)
+	b.notify(notify.KindAppStarted, appCfg.ID, "app %d %s/%s on %s started", appCfg.ID, appCfg.Base, appCfg.Quote, appCfg.Exchange)
+
+	return nil
+}
+
+// stopApp stops a running app, paused apps keep their orders live on exchange
+func (b *Bwd) stopApp(appID int, pause bool) {
+	a, ok := b.runningApps[appID]
+	if !ok {
+		return
+	}
+
+	action := "stopped"
+	if pause {
+		a.Pause()
+		action = "paused"
+	} else {
+		a.Stop()
+	}
+
+	appCfg := b.appConfigs[appID]
+	b.notify(notify.KindAppStopped, appID, "app %d %s/%s %s", appID, appCfg.Base, appCfg.Quote, action)
+
+	delete(b.runningApps, appID)
+	delete(b.appConfigs, appID)
+}
+
+// handleCrashedApps stops apps whose trader panicked, they are started again with backoff
+// orders are left live, restarted trader reconciles them
+func (b *Bwd) handleCrashedApps(apps []storage.App) {
+	for _, appCfg := range apps {
+		a, ok := b.runningApps[appCfg.ID]
+		if !ok {
+			continue
+		}
+
+		crashErr := a.CrashErr()
+		if crashErr == nil {
+			continue
+		}
+
+		logger := b.logger.WithField("appid", appCfg.ID)
+		logger.WithError(crashErr).Error("try stop crashed app")
+		b.stopApp(appCfg.ID, true)
+		b.registerStartFailure(appCfg)
+		b.setRuntimeStatus(appCfg, runtimeStatusCrashed, crashErr)
+		b.notify(notify.KindAppFailed, appCfg.ID, "app %d %s/%s crashed: %s", appCfg.ID, appCfg.Base, appCfg.Quote, crashErr.Error())
+		logger.Info("success stop crashed app")
+	}
+}
+
+// stopRemovedApps stops running apps that do not exist anymore in storage
+func (b *Bwd) stopRemovedApps(apps []storage.App) {
+	exists := make(map[int]bool)
+	for _, a := range apps {
+		exists[a.ID] = true
+	}
+
+	for appID := range b.runningApps {
+		if exists[appID] {
+			continue
+		}
+
+		logger := b.logger.WithField("appid", appID)
+		logger.Info("try stop app removed from storage")
+		b.stopApp(appID, false)
+		logger.Info("success stop app removed from storage")
+	}
+
+	for appID := range b.appConnectors {
+		if !exists[appID] {
+			delete(b.appConnectors, appID)
+		}
+	}
+}
+
+// setRuntimeStatus writes observed app state back to storage, only when changed
+func (b *Bwd) setRuntimeStatus(appCfg storage.App, status string, err error) {
+	runtime := storage.AppRuntime{
+		Status:        status,
+		StartAttempts: b.startAttempts(appCfg.ID),
+	}
+	if err != nil {
+		runtime.LastError = err.Error()
+		runtime.LastErrorAt = time.Now().UTC()
+	}
+
+	if appCfg.RuntimeStatus == runtime.Status &&
+		appCfg.LastError == runtime.LastError &&
+		appCfg.StartAttempts == runtime.StartAttempts {
+		return
+	}
+
+	if err := b.storer.UpdateAppRuntime(appCfg.ID, runtime); err != nil {
+		b.logger.WithError(err).WithField("appid", appCfg.ID).Error("fail update app runtime status")
 	}
 }
 
-func (b *Bwd) createApp(a storage.App) *app.App {
+func (b *Bwd) createApp(a storage.App, c connector.Connector) *app.App {
 	appCfg := &app.ConfigApp{
 		Storer:             b.storer,
-		Connector:          b.connectors[a.Exchange],
+		Connector:          c,
 		Interval:           a.Interval,
 		ID:                 a.ID,
 		Exchange:           a.Exchange,
@@ -195,6 +529,13 @@ func (b *Bwd) createApp(a storage.App) *app.App {
 		CompoundType:       a.CompoundType,
 		CompoundDetails:    a.CompoundDetails,
 		PublishOrderNumber: a.PublishOrderNumber,
+		Direction:          a.Direction,
+		SkimPercent:        a.SkimPercent,
+		QuoteBudget:        a.QuoteBudget,
+		QuotePercentUse:    a.QuotePercentUse,
+		StopPolicy:         a.StopPolicy,
+		WindingDown:        a.Status == appStatusWindingDown,
+		Events:             b.events,
 	}
 
 	return app.New(appCfg, b.logger)
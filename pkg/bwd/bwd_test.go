@@ -0,0 +1,202 @@
+package bwd
+
+import (
+	"bwd/pkg/app"
+	"bwd/pkg/connector"
+	"bwd/pkg/storage"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeStorer keeps runtime updates and reports in memory, methods not overridden panic
+type fakeStorer struct {
+	storage.Storer
+	runtimes map[int]storage.AppRuntime
+	reports  []storage.DailyReport
+	// app whose trades can not be fetched
+	failAppID int
+}
+
+func (f *fakeStorer) UpdateAppRuntime(appID int, runtime storage.AppRuntime) error {
+	if f.runtimes == nil {
+		f.runtimes = make(map[int]storage.AppRuntime)
+	}
+	f.runtimes[appID] = runtime
+	return nil
+}
+
+func (f *fakeStorer) ClearAppDone(appID int) error {
+	return nil
+}
+
+func (f *fakeStorer) BalanceHistoryBefore(appID int, before time.Time) (storage.BalanceHistory, error) {
+	return storage.BalanceHistory{}, nil
+}
+
+func (f *fakeStorer) ClosedTradesCount(appID int, from, to time.Time) (int, error) {
+	return 1, nil
+}
+
+func (f *fakeStorer) ActiveTrades(appID int) ([]storage.Trade, error) {
+	if appID == f.failAppID {
+		return nil, errors.New("connection lost")
+	}
+	return nil, nil
+}
+
+func (f *fakeStorer) AddDailyReport(report storage.DailyReport) error {
+	f.reports = append(f.reports, report)
+	return nil
+}
+
+// fakeApp records how orchestrator stops it, Stop is the call applying stop policy
+type fakeApp struct {
+	windingDown bool
+	started     int
+	paused      int
+	stopped     int
+	live        []app.LiveConfig
+}
+
+func (f *fakeApp) Start() error                         { f.started++; return nil }
+func (f *fakeApp) Pause()                               { f.paused++ }
+func (f *fakeApp) Stop()                                { f.stopped++ }
+func (f *fakeApp) WindDown()                            { f.windingDown = true }
+func (f *fakeApp) IsWindingDown() bool                  { return f.windingDown }
+func (f *fakeApp) CrashErr() error                      { return nil }
+func (f *fakeApp) Reconfigure(cfg app.LiveConfig) error { f.live = append(f.live, cfg); return nil }
+func (f *fakeApp) Steps() []float64                     { return nil }
+
+// newTestBwd returns bwd whose apps are fakes, created apps are appended to apps
+func newTestBwd(apps *[]*fakeApp) *Bwd {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	b := New(context.Background(), &ConfigBwd{Interval: time.Second}, logger)
+	b.storer = &fakeStorer{}
+	b.newApp = func(appCfg storage.App, c connector.Connector) runningApp {
+		a := &fakeApp{windingDown: appCfg.Status == appStatusWindingDown}
+		*apps = append(*apps, a)
+		return a
+	}
+
+	return b
+}
+
+func testAppConfig(status string) storage.App {
+	return storage.App{
+		ID:                 1,
+		Exchange:           "FAKE",
+		Base:               "BTC",
+		Quote:              "USDT",
+		Interval:           time.Second,
+		PublishOrderNumber: 1,
+		StopPolicy:         "CANCEL_ALL",
+		Status:             status,
+	}
+}
+
+func TestApplyAppConfigStatusChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		// first app is the one started with from status
+		wantPaused  int
+		wantStopped int
+		wantApps    int
+	}{
+		{name: "resume winding down app keeps orders", from: appStatusWindingDown, to: appStatusActive, wantPaused: 1, wantApps: 2},
+		{name: "pause keeps orders", from: appStatusActive, to: appStatusPaused, wantPaused: 1, wantApps: 1},
+		{name: "deactivate applies stop policy", from: appStatusActive, to: appStatusInactive, wantStopped: 1, wantApps: 1},
+		{name: "wind down keeps app running", from: appStatusActive, to: appStatusWindingDown, wantApps: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apps []*fakeApp
+			b := newTestBwd(&apps)
+
+			b.applyAppConfig(testAppConfig(tt.from))
+			b.applyAppConfig(testAppConfig(tt.to))
+
+			if len(apps) != tt.wantApps {
+				t.Fatalf("expected apps created: %d, got: %d", tt.wantApps, len(apps))
+			}
+			first := apps[0]
+			if first.paused != tt.wantPaused || first.stopped != tt.wantStopped {
+				t.Errorf("expected paused: %d stopped: %d, got paused: %d stopped: %d",
+					tt.wantPaused, tt.wantStopped, first.paused, first.stopped)
+			}
+		})
+	}
+}
+
+func TestReconfigureApp(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(a *storage.App)
+		// wantRestart is true when first app is paused and a new one is started
+		wantRestart bool
+	}{
+		{name: "fees applied live", change: func(a *storage.App) { a.LimitOrderFees = 0.1 }},
+		{name: "publish order number restarts", change: func(a *storage.App) { a.PublishOrderNumber = 2 }, wantRestart: true},
+		{name: "stop policy restarts", change: func(a *storage.App) { a.StopPolicy = "CANCEL_BUYS" }, wantRestart: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apps []*fakeApp
+			b := newTestBwd(&apps)
+
+			appCfg := testAppConfig(appStatusActive)
+			b.applyAppConfig(appCfg)
+			tt.change(&appCfg)
+			b.applyAppConfig(appCfg)
+
+			first := apps[0]
+			if first.stopped != 0 {
+				t.Errorf("expected stop policy not applied, got stopped: %d", first.stopped)
+			}
+
+			if !tt.wantRestart {
+				if len(apps) != 1 || len(first.live) != 1 {
+					t.Fatalf("expected live reconfigure, got apps: %d live: %d", len(apps), len(first.live))
+				}
+				return
+			}
+
+			if len(apps) != 2 || first.paused != 1 || len(first.live) != 0 {
+				t.Errorf("expected restart, got apps: %d paused: %d live: %d", len(apps), first.paused, len(first.live))
+			}
+		})
+	}
+}
+
+func TestDailyReportAppFailure(t *testing.T) {
+	var apps []*fakeApp
+	b := newTestBwd(&apps)
+	st := &fakeStorer{failAppID: 1}
+	b.storer = st
+	b.reportEnabled = true
+
+	b.dailyReport([]storage.App{
+		{ID: 1, Base: "BTC", Quote: "USDT", Status: appStatusActive},
+		{ID: 2, Base: "ETH", Quote: "USDT", Status: appStatusActive},
+	})
+
+	if len(st.reports) != 1 || st.reports[0].AppID != 2 {
+		t.Fatalf("expected report of app 2 only, got: %+v", st.reports)
+	}
+	if st.reports[0].CapitalLockedAsset != "USDT" {
+		t.Errorf("expected capital locked in USDT, got: %q", st.reports[0].CapitalLockedAsset)
+	}
+	if want := lastReportTime(time.Now(), 0); !b.reportLastEnd.Equal(want) {
+		t.Errorf("expected period end advanced to: %s, got: %s", want, b.reportLastEnd)
+	}
+}
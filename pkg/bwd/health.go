@@ -0,0 +1,145 @@
+package bwd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// orchestrator is not ready when its latest run is older than this number of intervals
+	maxMissedRuns = 3
+)
+
+// check is the result of a readiness check
+type check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type readiness struct {
+	Ready  bool    `json:"ready"`
+	Checks []check `json:"checks"`
+}
+
+// HealthHandler reports process is alive
+func (b *Bwd) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealthJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+}
+
+// ReadyHandler reports if storage is reachable, connectors are synced and
+// ACTIVE apps are running, 503 when any check fails
+func (b *Bwd) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := b.readiness()
+
+		status := http.StatusOK
+		if !res.Ready {
+			status = http.StatusServiceUnavailable
+		}
+
+		writeHealthJSON(w, status, res)
+	})
+}
+
+func (b *Bwd) readiness() readiness {
+	b.stateMu.RLock()
+	s := b.state
+	b.stateMu.RUnlock()
+
+	// state is published by first run after Start, storer is not set before
+	if s.publishedAt.IsZero() {
+		return readiness{Checks: []check{{Name: "bwd", Detail: "not started"}}}
+	}
+
+	checks := []check{
+		b.storageCheck(),
+		b.orchestratorCheck(s),
+	}
+	checks = append(checks, connectorChecks(s)...)
+	checks = append(checks, activeAppsCheck(s))
+
+	res := readiness{Ready: true, Checks: checks}
+	for _, c := range checks {
+		if !c.OK {
+			res.Ready = false
+		}
+	}
+
+	return res
+}
+
+func (b *Bwd) storageCheck() check {
+	c := check{Name: "storage"}
+
+	if err := b.storer.Ping(); err != nil {
+		c.Detail = err.Error()
+		return c
+	}
+
+	c.OK = true
+	return c
+}
+
+func (b *Bwd) orchestratorCheck(s state) check {
+	c := check{Name: "orchestrator"}
+
+	age := time.Since(s.publishedAt)
+	if age > time.Duration(maxMissedRuns)*b.interval+connectorStopTimeout {
+		c.Detail = fmt.Sprintf("latest run %s ago", age.Round(time.Second))
+		return c
+	}
+
+	c.OK = true
+	return c
+}
+
+func connectorChecks(s state) []check {
+	keys := make([]string, 0, len(s.connectorsHealth))
+	for key := range s.connectorsHealth {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	checks := make([]check, 0, len(keys))
+	for _, key := range keys {
+		c := check{Name: "connector:" + key, OK: true}
+		if reason := connectorUnhealthyReason(s.connectorsHealth[key]); reason != "" {
+			c.OK = false
+			c.Detail = reason
+		}
+		checks = append(checks, c)
+	}
+
+	return checks
+}
+
+func activeAppsCheck(s state) check {
+	c := check{Name: "apps", OK: true}
+
+	var notRunning []string
+	for _, appID := range s.activeApps {
+		if !s.runningApps[appID] {
+			notRunning = append(notRunning, fmt.Sprintf("%d", appID))
+		}
+	}
+
+	if len(notRunning) > 0 {
+		c.OK = false
+		c.Detail = "active apps not running: " + strings.Join(notRunning, ",")
+	}
+
+	return c
+}
+
+func writeHealthJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,155 @@
+package bwd
+
+import (
+	"bwd/pkg/event"
+	"bwd/pkg/notify"
+	"bwd/pkg/storage"
+	"bwd/pkg/utils/text"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// order errors of an app within window that trigger a notification
+	orderErrorsThreshold = 5
+	orderErrorsWindow    = 10 * time.Minute
+	// events buffered for notifier, notifications are best effort
+	notifyEventsBufferSize = 1024
+	// notify_dead_letters.error column size
+	deadLetterMaxError = 1024
+)
+
+// orderErrors tracks recent order failures of an app
+type orderErrors struct {
+	at        []time.Time
+	alertedAt time.Time
+}
+
+// startNotifier creates a channel per notify config entry, SLACK_HOOK adds
+// a slack channel receiving everything
+func (b *Bwd) startNotifier() error {
+	var channels []notify.ChannelConfig
+	if b.slackHook != "" {
+		channels = append(channels, notify.ChannelConfig{Name: "slack", Type: notify.TypeSlack, URL: b.slackHook})
+	}
+
+	if b.notifyConfig != "" {
+		cfgs, err := notify.LoadChannels(b.notifyConfig)
+		if err != nil {
+			return err
+		}
+		channels = append(channels, cfgs...)
+	}
+
+	if len(channels) == 0 {
+		return nil
+	}
+
+	router := notify.NewRouter()
+	for _, c := range channels {
+		n, err := notify.NewNotifier(c)
+		if err != nil {
+			return fmt.Errorf("notify channel %s, err: %w", c.Name, err)
+		}
+
+		router.Add(c.Route, notify.NewDispatcher(&notify.ConfigDispatcher{
+			Notifier:   n,
+			DeadLetter: b.storeDeadLetter,
+		}, b.logger))
+	}
+
+	b.notifier = router
+	b.notifier.Start()
+
+	b.notifyEvents = b.events.Subscribe(nil, notifyEventsBufferSize)
+	go b.forwardEvents(b.notifyEvents)
+
+	return nil
+}
+
+// stopNotifier flushes pending notifications
+func (b *Bwd) stopNotifier() {
+	if b.notifier == nil {
+		return
+	}
+
+	b.notifyEvents.Unsubscribe()
+	b.notifier.Stop()
+}
+
+// storeDeadLetter keeps undelivered messages so they can be replayed by hand
+func (b *Bwd) storeDeadLetter(dl notify.DeadLetter) {
+	payload, err := json.Marshal(dl.Message.Notifications)
+	if err != nil {
+		b.logger.WithError(err).Error("fail encode notify dead letter")
+	}
+
+	errText := text.Truncate(dl.Error, deadLetterMaxError)
+
+	entry := storage.NotifyDeadLetter{
+		Channel:   dl.Channel,
+		Text:      dl.Message.Text,
+		Payload:   string(payload),
+		Attempts:  dl.Attempts,
+		Error:     errText,
+		CreatedAt: dl.CreatedAt,
+	}
+
+	if err := b.storer.AddNotifyDeadLetter(entry); err != nil {
+		b.logger.WithError(err).WithField("channel", dl.Channel).Error("fail store notify dead letter")
+	}
+}
+
+func (b *Bwd) notify(kind string, appID int, format string, args ...interface{}) {
+	if b.notifier == nil {
+		return
+	}
+
+	b.notifier.Notify(notify.Notification{
+		Kind:  kind,
+		AppID: appID,
+		Text:  fmt.Sprintf(format, args...),
+	})
+}
+
+// forwardEvents turns trader events into notifications until subscription is closed
+func (b *Bwd) forwardEvents(sub *event.Subscription) {
+	errs := make(map[int]*orderErrors)
+
+	for e := range sub.C {
+		switch e.Type {
+		case event.TypeTradeClosed:
+			b.notify(notify.KindTradeClosed, e.AppID, "app %d trade %d closed at %v, profit %v %s",
+				e.AppID, e.TradeID, e.Price, e.Amount, e.Asset)
+		case event.TypeOrderFailed:
+			oe, ok := errs[e.AppID]
+			if !ok {
+				oe = &orderErrors{}
+				errs[e.AppID] = oe
+			}
+			b.trackOrderError(oe, e)
+		}
+	}
+}
+
+// trackOrderError notifies once per window when an app keeps failing orders
+func (b *Bwd) trackOrderError(oe *orderErrors, e event.Event) {
+	now := time.Now()
+
+	recent := oe.at[:0]
+	for _, at := range oe.at {
+		if now.Sub(at) < orderErrorsWindow {
+			recent = append(recent, at)
+		}
+	}
+	oe.at = append(recent, now)
+
+	if len(oe.at) < orderErrorsThreshold || now.Sub(oe.alertedAt) < orderErrorsWindow {
+		return
+	}
+
+	oe.alertedAt = now
+	b.notify(notify.KindOrderErrors, e.AppID, "app %d: %d order errors in last %s, latest: %s",
+		e.AppID, len(oe.at), orderErrorsWindow, e.Error)
+}
@@ -0,0 +1,137 @@
+package bwd
+
+import (
+	"bwd/pkg/app"
+	"bwd/pkg/storage"
+	"bwd/pkg/utils/metrics/exporter"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	reconfigureModeLive    = "live"
+	reconfigureModeRestart = "restart"
+)
+
+var (
+	metricAppReconfigure = exporter.GetCounter("bwd", "app_reconfigure_count", []string{"appid", "mode"})
+)
+
+var (
+	// app fields that can be applied on a running app
+	liveAppFields = map[string]bool{
+		"Interval":        true,
+		"MarketOrderFees": true,
+		"LimitOrderFees":  true,
+	}
+	// app fields handled by status logic or written by bwd, not by reconfiguration
+	statusAppFields = map[string]bool{
+		"Status":        true,
+		"IsDone":        true,
+		"RuntimeStatus": true,
+		"LastError":     true,
+		"LastErrorAt":   true,
+		"StartAttempts": true,
+	}
+)
+
+// reconfigureApp applies storage changes on a running app, live when possible
+// otherwise app is restarted with new config
+func (b *Bwd) reconfigureApp(a runningApp, appCfg storage.App, logger logrus.FieldLogger) {
+	prevCfg, ok := b.appConfigs[appCfg.ID]
+	if !ok || appConfigHash(prevCfg) == appConfigHash(appCfg) {
+		return
+	}
+
+	diff, requiresRestart := appConfigDiff(prevCfg, appCfg)
+	logger = logger.WithField("datadiff", fmt.Sprintf("%v", diff))
+
+	mode := reconfigureModeLive
+	if requiresRestart {
+		mode = reconfigureModeRestart
+	}
+	labels := prometheus.Labels{"appid": strconv.Itoa(appCfg.ID), "mode": mode}
+
+	if !requiresRestart {
+		err := a.Reconfigure(app.LiveConfig{
+			Interval:        appCfg.Interval,
+			MarketOrderFees: appCfg.MarketOrderFees,
+			LimitOrderFees:  appCfg.LimitOrderFees,
+		})
+		if err != nil {
+			logger.WithError(err).Error("fail live reconfigure app")
+			return
+		}
+
+		b.appConfigs[appCfg.ID] = appCfg
+		metricAppReconfigure.With(labels).Inc()
+		logger.Info("success live reconfigure app")
+		return
+	}
+
+	// orders stay live, restarted trader reconciles them
+	logger.Info("try restart app with new config")
+	b.stopApp(appCfg.ID, true)
+
+	if err := b.startApp(appCfg); err != nil {
+		// app stays stopped, next run will retry start with current config
+		logger.WithError(err).Error("fail restart app with new config")
+		return
+	}
+
+	metricAppReconfigure.With(labels).Inc()
+	logger.Info("success restart app with new config")
+}
+
+// appConfigHash returns hash of app config, status fields are ignored
+func appConfigHash(a storage.App) string {
+	a.Status = ""
+	a.IsDone = false
+	a.RuntimeStatus = ""
+	a.LastError = ""
+	a.LastErrorAt = time.Time{}
+	a.StartAttempts = 0
+
+	j, _ := json.Marshal(a)
+	sum := sha256.Sum256(j)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// appConfigDiff returns changed fields as "field: old -> new" and if any of them can not be applied live
+func appConfigDiff(prev, next storage.App) ([]string, bool) {
+	var diff []string
+	var requiresRestart bool
+
+	prevValue := reflect.ValueOf(prev)
+	nextValue := reflect.ValueOf(next)
+	appType := prevValue.Type()
+
+	for i := 0; i < appType.NumField(); i++ {
+		name := appType.Field(i).Name
+		if statusAppFields[name] {
+			continue
+		}
+
+		p := prevValue.Field(i).Interface()
+		n := nextValue.Field(i).Interface()
+		if reflect.DeepEqual(p, n) {
+			continue
+		}
+
+		diff = append(diff, fmt.Sprintf("%s: %v -> %v", name, p, n))
+		if !liveAppFields[name] {
+			requiresRestart = true
+		}
+	}
+
+	return diff, requiresRestart
+}
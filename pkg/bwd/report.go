@@ -0,0 +1,153 @@
+package bwd
+
+import (
+	"bwd/pkg/ledger"
+	"bwd/pkg/notify"
+	"bwd/pkg/storage"
+	"bwd/pkg/utils/metrics/exporter"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const reportPeriod = 24 * time.Hour
+
+var (
+	metricDailyReportFailure = exporter.GetCounter("bwd", "daily_report_failure_count", []string{})
+)
+
+// parseReportTime parses a HH:MM UTC time of day, empty disables daily reports
+func parseReportTime(value string) (time.Duration, bool, error) {
+	if value == "" {
+		return 0, false, nil
+	}
+
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid daily report time %q, expected HH:MM", value)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, true, nil
+}
+
+// lastReportTime returns latest scheduled report time not after now
+func lastReportTime(now time.Time, at time.Duration) time.Time {
+	now = now.UTC()
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(at)
+	if scheduled.After(now) {
+		scheduled = scheduled.Add(-reportPeriod)
+	}
+	return scheduled
+}
+
+// initDailyReport loads end of latest stored report so a missed report is
+// generated once after restart
+func (b *Bwd) initDailyReport() error {
+	at, enabled, err := parseReportTime(b.dailyReportTime)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+
+	lastEnd, err := b.storer.LatestDailyReportEnd()
+	if err != nil {
+		return fmt.Errorf("fail fetch latest daily report, err: %w", err)
+	}
+
+	b.reportAt = at
+	b.reportEnabled = true
+	b.reportLastEnd = lastEnd
+
+	return nil
+}
+
+// dailyReport generates report of the previous period when scheduled time is
+// passed, an app failing its report is listed as failed and not retried so
+// other apps are still reported
+func (b *Bwd) dailyReport(apps []storage.App) {
+	if !b.reportEnabled {
+		return
+	}
+
+	end := lastReportTime(time.Now(), b.reportAt)
+	if !end.After(b.reportLastEnd) {
+		return
+	}
+	start := end.Add(-reportPeriod)
+
+	l := ledger.New(b.storer)
+
+	var lines []string
+	for _, a := range apps {
+		r, err := l.DailyReport(a, start, end)
+		if err != nil {
+			metricDailyReportFailure.With(prometheus.Labels{}).Inc()
+			b.logger.WithError(err).WithField("appid", a.ID).Error("fail generate daily report")
+			lines = append(lines, reportFailedLine(a))
+			continue
+		}
+
+		if !isReportable(a, r) {
+			continue
+		}
+
+		if err := b.storer.AddDailyReport(r); err != nil {
+			metricDailyReportFailure.With(prometheus.Labels{}).Inc()
+			b.logger.WithError(err).WithField("appid", a.ID).Error("fail store daily report")
+		}
+
+		lines = append(lines, reportLine(a, r))
+	}
+
+	b.reportLastEnd = end
+	b.logger.WithField("period_end", end).Infof("daily report generated for %d apps", len(lines))
+
+	if len(lines) == 0 {
+		lines = append(lines, "no app activity")
+	}
+
+	b.notify(notify.KindDailyReport, 0, "daily report %s - %s UTC\n%s",
+		start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"), strings.Join(lines, "\n"))
+}
+
+// isReportable skips apps that are stopped and had nothing going on
+func isReportable(a storage.App, r storage.DailyReport) bool {
+	if a.Status != appStatusInactive {
+		return true
+	}
+
+	return r.TradesClosed > 0 || r.OpenTrades > 0 || r.NetProfit != 0 || r.BaseNetProfit != 0
+}
+
+func reportLine(a storage.App, r storage.DailyReport) string {
+	line := fmt.Sprintf("• app %d %s/%s: %d trades closed, net profit %s %s, reinvested %s %s",
+		a.ID, a.Base, a.Quote, r.TradesClosed,
+		formatAmount(r.NetProfit), a.Quote, formatAmount(r.Reinvested), a.Quote)
+
+	if r.BaseNetProfit != 0 || r.BaseReinvested != 0 {
+		line += fmt.Sprintf(" (base %s %s, reinvested %s %s)",
+			formatAmount(r.BaseNetProfit), a.Base, formatAmount(r.BaseReinvested), a.Base)
+	}
+
+	return line + fmt.Sprintf(", %d open trades, %s %s locked",
+		r.OpenTrades, formatAmount(r.CapitalLocked), r.CapitalLockedAsset)
+}
+
+func reportFailedLine(a storage.App) string {
+	return fmt.Sprintf("• app %d %s/%s: report failed, see logs", a.ID, a.Base, a.Quote)
+}
+
+// formatAmount rounds to 8 decimals and trims trailing zeros
+func formatAmount(v float64) string {
+	s := strings.TrimRight(strconv.FormatFloat(v, 'f', 8, 64), "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
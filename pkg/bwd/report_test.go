@@ -0,0 +1,164 @@
+package bwd
+
+import (
+	"bwd/pkg/storage"
+	"bwd/pkg/trader"
+	"testing"
+	"time"
+)
+
+func TestParseReportTime(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		want        time.Duration
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{name: "empty disables", value: ""},
+		{name: "midnight", value: "00:00", want: 0, wantEnabled: true},
+		{name: "morning", value: "08:30", want: 8*time.Hour + 30*time.Minute, wantEnabled: true},
+		{name: "end of day", value: "23:59", want: 23*time.Hour + 59*time.Minute, wantEnabled: true},
+		{name: "hour out of range", value: "24:00", wantErr: true},
+		{name: "missing minutes", value: "8", wantErr: true},
+		{name: "with seconds", value: "08:30:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, enabled, err := parseReportTime(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got: %v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want || enabled != tt.wantEnabled {
+				t.Errorf("expected: %v enabled: %v, got: %v enabled: %v", tt.want, tt.wantEnabled, got, enabled)
+			}
+		})
+	}
+}
+
+func TestLastReportTime(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	at := 8 * time.Hour
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "after scheduled time",
+			now:  time.Date(2021, 3, 10, 9, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 3, 10, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "at scheduled time",
+			now:  time.Date(2021, 3, 10, 8, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 3, 10, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "before scheduled time",
+			now:  time.Date(2021, 3, 10, 7, 59, 0, 0, time.UTC),
+			want: time.Date(2021, 3, 9, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "before scheduled time on first day of month",
+			now:  time.Date(2021, 3, 1, 1, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 2, 28, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "local time is converted to utc",
+			now:  time.Date(2021, 3, 10, 8, 30, 0, 0, cet),
+			want: time.Date(2021, 3, 9, 8, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastReportTime(tt.now, at); !got.Equal(tt.want) {
+				t.Errorf("expected: %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReportLine(t *testing.T) {
+	tests := []struct {
+		name string
+		app  storage.App
+		r    storage.DailyReport
+		want string
+	}{
+		{
+			name: "buy sell locks quote",
+			app:  storage.App{ID: 1, Base: "BTC", Quote: "USDT", Direction: trader.DirectionBuySell},
+			r:    storage.DailyReport{TradesClosed: 2, NetProfit: 1.5, Reinvested: 0.75, OpenTrades: 3, CapitalLocked: 300, CapitalLockedAsset: "USDT"},
+			want: "• app 1 BTC/USDT: 2 trades closed, net profit 1.5 USDT, reinvested 0.75 USDT, 3 open trades, 300 USDT locked",
+		},
+		{
+			name: "sell buy locks base",
+			app:  storage.App{ID: 2, Base: "BTC", Quote: "USDT", Direction: trader.DirectionSellBuy},
+			r:    storage.DailyReport{BaseNetProfit: 0.0001, OpenTrades: 1, CapitalLocked: 0.01, CapitalLockedAsset: "BTC"},
+			want: "• app 2 BTC/USDT: 0 trades closed, net profit 0 USDT, reinvested 0 USDT (base 0.0001 BTC, reinvested 0 BTC), 1 open trades, 0.01 BTC locked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reportLine(tt.app, tt.r); got != tt.want {
+				t.Errorf("expected: %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{v: 0, want: "0"},
+		{v: 10, want: "10"},
+		{v: 1.23, want: "1.23"},
+		{v: 0.000000001, want: "0"},
+		{v: -0.000000001, want: "0"},
+		{v: -2.5, want: "-2.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatAmount(tt.v); got != tt.want {
+				t.Errorf("expected: %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsReportable(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		r      storage.DailyReport
+		want   bool
+	}{
+		{name: "active app without activity", status: appStatusActive, want: true},
+		{name: "inactive app without activity", status: appStatusInactive, want: false},
+		{name: "inactive app with open trades", status: appStatusInactive, r: storage.DailyReport{OpenTrades: 1}, want: true},
+		{name: "inactive app with profit", status: appStatusInactive, r: storage.DailyReport{NetProfit: 1}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isReportable(storage.App{Status: tt.status}, tt.r); got != tt.want {
+				t.Errorf("expected: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package bwd
+
+import (
+	"bwd/pkg/connector"
+	"bwd/pkg/storage"
+	"errors"
+	"time"
+)
+
+// state is a snapshot of orchestrator state published after each run, it is
+// read concurrently by web api while runningApps and connectors are not
+type state struct {
+	runningApps      map[int]bool
+	appConnectors    map[int]string
+	appSteps         map[int][]float64
+	connectorsHealth map[string]connector.Health
+	connectors       map[string]connector.Connector
+	accounts         map[int]storage.Account
+	activeApps       []int
+	publishedAt      time.Time
+}
+
+func (b *Bwd) publishState(apps []storage.App) {
+	s := state{
+		runningApps:      make(map[int]bool),
+		appConnectors:    make(map[int]string),
+		appSteps:         make(map[int][]float64),
+		connectorsHealth: make(map[string]connector.Health),
+		connectors:       make(map[string]connector.Connector),
+		accounts:         make(map[int]storage.Account),
+		publishedAt:      time.Now(),
+	}
+
+	for appID, a := range b.runningApps {
+		s.runningApps[appID] = true
+		s.appSteps[appID] = a.Steps()
+	}
+	for appID, key := range b.appConnectors {
+		s.appConnectors[appID] = key
+	}
+	for key, c := range b.connectors {
+		s.connectorsHealth[key] = c.Health()
+		s.connectors[key] = c
+	}
+	for id, acc := range b.accounts {
+		s.accounts[id] = acc
+	}
+	for _, a := range apps {
+		if a.Status == appStatusActive {
+			s.activeApps = append(s.activeApps, a.ID)
+		}
+	}
+
+	b.stateMu.Lock()
+	b.state = s
+	b.stateMu.Unlock()
+}
+
+// IsAppRunning reports if app was running at latest run
+func (b *Bwd) IsAppRunning(appID int) bool {
+	b.stateMu.RLock()
+	defer b.stateMu.RUnlock()
+
+	return b.state.runningApps[appID]
+}
+
+// AppConnector returns connector key used by app at latest run
+func (b *Bwd) AppConnector(appID int) string {
+	b.stateMu.RLock()
+	defer b.stateMu.RUnlock()
+
+	return b.state.appConnectors[appID]
+}
+
+// AppSteps returns grid levels of app running at latest run
+func (b *Bwd) AppSteps(appID int) []float64 {
+	b.stateMu.RLock()
+	defer b.stateMu.RUnlock()
+
+	return b.state.appSteps[appID]
+}
+
+// ConnectorsHealth returns health of connectors at latest run
+func (b *Bwd) ConnectorsHealth() map[string]connector.Health {
+	b.stateMu.RLock()
+	defer b.stateMu.RUnlock()
+
+	health := make(map[string]connector.Health, len(b.state.connectorsHealth))
+	for key, h := range b.state.connectorsHealth {
+		health[key] = h
+	}
+
+	return health
+}
+
+// ValidateApp checks app config against exchange without starting it, app
+// account connector should be running
+func (b *Bwd) ValidateApp(appCfg storage.App) error {
+	b.stateMu.RLock()
+	acc, err := appAccount(b.state.accounts, appCfg)
+	c, ok := b.state.connectors[connectorKey(acc)]
+	b.stateMu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errors.New("no running connector for app account, it is created when an app of the account is loaded")
+	}
+
+	a := b.createApp(appCfg, c)
+	defer a.Close()
+
+	return a.Validate()
+}
@@ -0,0 +1,135 @@
+package bwd
+
+import (
+	"bwd/pkg/connector"
+	"bwd/pkg/notify"
+	"bwd/pkg/storage"
+	"bwd/pkg/utils/metrics/exporter"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// connector is unhealthy when it did not sync with exchange for this duration
+	connectorMaxSyncAge = time.Minute
+	// connector is unhealthy after this number of consecutive loop errors
+	connectorMaxConsecutiveErrors = 10
+	// connector loop that does not stop in this duration is abandoned
+	connectorStopTimeout = 30 * time.Second
+)
+
+var (
+	metricConnectorRestart = exporter.GetCounter("bwd", "connector_restart_count", []string{"connector"})
+	metricConnectorHealthy = exporter.GetGauge("bwd", "connector_healthy", []string{"connector"})
+)
+
+// connectorUnhealthyReason returns why connector is unhealthy, empty when healthy
+func connectorUnhealthyReason(h connector.Health) string {
+	if age := time.Since(h.LastSyncAt); age > connectorMaxSyncAge {
+		return fmt.Sprintf("last sync %s ago", age.Round(time.Second))
+	}
+
+	if h.ConsecutiveErrors >= connectorMaxConsecutiveErrors {
+		return fmt.Sprintf("%d consecutive errors", h.ConsecutiveErrors)
+	}
+
+	return ""
+}
+
+// superviseConnectors stops unhealthy connectors and pauses apps using them,
+// connector and apps are started again by the next runs
+func (b *Bwd) superviseConnectors(apps []storage.App) {
+	for key, c := range b.connectors {
+		labels := prometheus.Labels{"connector": key}
+
+		reason := connectorUnhealthyReason(c.Health())
+		if reason == "" {
+			metricConnectorHealthy.With(labels).Set(1)
+			continue
+		}
+		metricConnectorHealthy.With(labels).Set(0)
+
+		b.restartConnector(key, apps, fmt.Errorf("connector %s unhealthy: %s", key, reason))
+	}
+}
+
+// restartConnector stops connector and pauses apps using it, orders stay live
+// while connector is down, connector and apps are started again by the next runs
+func (b *Bwd) restartConnector(key string, apps []storage.App, reason error) {
+	c, ok := b.connectors[key]
+	if !ok {
+		return
+	}
+
+	logger := b.logger.WithField("dataconnector", key)
+	logger.WithError(reason).Error("try restart connector")
+	b.notify(notify.KindConnectorDown, 0, "%s, restarting connector and its apps", reason.Error())
+
+	for _, appCfg := range apps {
+		if b.appConnectors[appCfg.ID] != key {
+			continue
+		}
+		if _, ok := b.runningApps[appCfg.ID]; !ok {
+			continue
+		}
+
+		b.stopApp(appCfg.ID, true)
+		b.setRuntimeStatus(appCfg, runtimeStatusStopped, reason)
+	}
+
+	b.stopConnector(key, c)
+	delete(b.connectors, key)
+	metricConnectorRestart.With(prometheus.Labels{"connector": key}).Inc()
+	logger.Info("connector stopped, will be created again")
+}
+
+// stopUnusedConnectors stops connectors no app resolves to anymore, ex: account
+// removed or apps moved to another account, apps still running on them are
+// stopped and started again on their new connector by the same run
+func (b *Bwd) stopUnusedConnectors(apps []storage.App) {
+	used := make(map[string]bool)
+	for _, appCfg := range apps {
+		acc, err := appAccount(b.accounts, appCfg)
+		if err != nil {
+			continue
+		}
+		used[connectorKey(acc)] = true
+	}
+
+	for key, c := range b.connectors {
+		if used[key] {
+			continue
+		}
+
+		for appID, appKey := range b.appConnectors {
+			if appKey != key {
+				continue
+			}
+			if _, ok := b.runningApps[appID]; ok {
+				b.stopApp(appID, true)
+			}
+			delete(b.appConnectors, appID)
+		}
+
+		b.stopConnector(key, c)
+		delete(b.connectors, key)
+		b.logger.WithField("dataconnector", key).Info("unused connector stopped")
+	}
+}
+
+// stopConnector waits for connector loop, a stalled loop is abandoned after timeout
+func (b *Bwd) stopConnector(key string, c connector.Connector) {
+	stopped := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(connectorStopTimeout):
+		b.logger.WithField("dataconnector", key).Error("connector stop timeout, loop abandoned")
+	}
+}
@@ -0,0 +1,83 @@
+package compound
+
+import (
+	"bwd/pkg/storage"
+	"bwd/pkg/utils/decimal"
+	"fmt"
+	"math"
+)
+
+type ConfigBaseProfitPercent struct {
+	AppID                  int
+	Storer                 storage.Storer
+	InitialStepQuoteVolume float64
+	MinBaseLotAllowed      float64
+	MaxBaseLotAllowed      float64
+	BaseLotTick            float64
+}
+
+// BaseProfitPercent compounds base profit earned by sell/buy (inverse) grids
+type BaseProfitPercent struct {
+	appID                  int
+	storer                 storage.Storer
+	initialStepQuoteVolume float64
+	minBaseLotAllowed      float64
+	maxBaseLotAllowed      float64
+	baseLotTick            float64
+}
+
+func NewBaseProfitPercent(cfg *ConfigBaseProfitPercent) *BaseProfitPercent {
+	return &BaseProfitPercent{
+		appID:                  cfg.AppID,
+		storer:                 cfg.Storer,
+		initialStepQuoteVolume: cfg.InitialStepQuoteVolume,
+		minBaseLotAllowed:      cfg.MinBaseLotAllowed,
+		maxBaseLotAllowed:      cfg.MaxBaseLotAllowed,
+		baseLotTick:            cfg.BaseLotTick,
+	}
+}
+
+// return:
+// total base volume for new trade
+// base compounded volume
+func (c *BaseProfitPercent) Volume(step float64) (float64, float64, error) {
+	// totalVolume source: initialStepVolume
+	totalVolume := c.initialStepQuoteVolume / step
+
+	// totalVolume source: latest closed trade
+	latestClosedTrade, err := c.storer.LatestAppClosedTradeByOpenPrice(c.appID, step)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	totalVolume = math.Max(totalVolume, latestClosedTrade.BaseVolume)
+
+	// get balance and see if we can add compound value
+	latestBalance, err := c.storer.LatestBalanceHistory(c.appID)
+	if err != nil {
+		return 0, 0, err
+	}
+	availableBaseVolume := latestBalance.TotalBaseNetIncome - latestBalance.TotalBaseReinvested
+
+	precision := decimal.Precision(c.baseLotTick)
+
+	var baseCompoundedVolume float64
+	if availableBaseVolume > 0 {
+		// only whole lot ticks can be added to an order
+		baseToCompound := math.Floor(availableBaseVolume/c.baseLotTick) * c.baseLotTick
+		if decimal.ToFixed(baseToCompound, precision) >= c.baseLotTick {
+			baseCompoundedVolume = decimal.ToFixed(baseToCompound, precision)
+			totalVolume = totalVolume + baseCompoundedVolume
+		}
+	}
+
+	if totalVolume > c.maxBaseLotAllowed || totalVolume < c.minBaseLotAllowed {
+		return 0, 0, fmt.Errorf("totalVolume: %v not in range: %v - %v",
+			totalVolume,
+			c.minBaseLotAllowed,
+			c.maxBaseLotAllowed,
+		)
+	}
+
+	return decimal.ToFixed(totalVolume, precision), baseCompoundedVolume, nil
+}
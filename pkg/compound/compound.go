@@ -1,35 +1,7 @@
 package compound
 
-import (
-	"math"
-	"strconv"
-	"strings"
-)
-
 type Compounder interface {
-	// returns baseVolumeWithCompoundAdded, quoteVolumeThatWasAddedAsCompound, error
+	// returns baseVolumeWithCompoundAdded, volumeThatWasAddedAsCompound, error
+	// compounded volume is expressed in the asset the grid earns (quote for buy/sell, base for sell/buy)
 	Volume(openBasePrice float64) (float64, float64, error)
 }
-
-func round(num float64) int {
-	return int(num + math.Copysign(0.5, num))
-}
-
-func toFixed(num float64, precision int) float64 {
-	output := math.Pow(10, float64(precision))
-	return float64(round(num*output)) / output
-}
-
-// floatPrecision returns the number of digits after .
-func floatPrecision(f float64) int {
-	strVal := strconv.FormatFloat(f, 'f', -1, 64)
-	split := strings.Split(strVal, ".")
-
-	// digits after floating point
-	daf := 0
-	if len(split) > 1 {
-		daf = len(split[1])
-	}
-
-	return daf
-}
@@ -0,0 +1,117 @@
+package compound
+
+import (
+	"bwd/pkg/utils/decimal"
+	"fmt"
+	"math"
+)
+
+const (
+	// DistanceWeightedLinear volume grows with factor for each step away from grid start: 1 + factor * distance
+	DistanceWeightedLinear = "LINEAR"
+	// DistanceWeightedGeometric volume is multiplied with factor for each step away from grid start: factor ^ distance
+	DistanceWeightedGeometric = "GEOMETRIC"
+)
+
+type ConfigDistanceWeighted struct {
+	InitialStepQuoteVolume float64
+	MinBaseLotAllowed      float64
+	MaxBaseLotAllowed      float64
+	BaseLotTick            float64
+	// QuoteMinVolume is exchange min notional of an order
+	QuoteMinVolume float64
+	// Steps of the grid, ordered from grid top to bottom
+	Steps []float64
+	// Inverse grids (sell/buy) measure distance from grid bottom, they sell more as price rises
+	Inverse bool
+	Mode    string
+	Factor  float64
+	// MaxQuoteCapital limits total quote volume of all steps, 0 means no limit
+	MaxQuoteCapital float64
+}
+
+// DistanceWeighted (pyramiding) increases step volume with distance from grid start,
+// grid top for buy/sell grids and grid bottom for sell/buy grids
+type DistanceWeighted struct {
+	minBaseLotAllowed float64
+	maxBaseLotAllowed float64
+	baseLotTick       float64
+	// base volume for each step
+	volumes map[float64]float64
+}
+
+func NewDistanceWeighted(cfg *ConfigDistanceWeighted) (*DistanceWeighted, error) {
+	c := &DistanceWeighted{
+		minBaseLotAllowed: cfg.MinBaseLotAllowed,
+		maxBaseLotAllowed: cfg.MaxBaseLotAllowed,
+		baseLotTick:       cfg.BaseLotTick,
+		volumes:           make(map[float64]float64),
+	}
+
+	quoteVolumes := make([]float64, len(cfg.Steps))
+	var totalQuoteVolume float64
+
+	for i, step := range cfg.Steps {
+		distance := i
+		if cfg.Inverse {
+			distance = len(cfg.Steps) - 1 - i
+		}
+
+		var weight float64
+		switch cfg.Mode {
+		case DistanceWeightedLinear:
+			weight = 1 + cfg.Factor*float64(distance)
+		case DistanceWeightedGeometric:
+			weight = math.Pow(cfg.Factor, float64(distance))
+		default:
+			return nil, fmt.Errorf("unknown distance weighted mode: %s", cfg.Mode)
+		}
+
+		// base volume can not exceed exchange max lot
+		quoteVolumes[i] = math.Min(cfg.InitialStepQuoteVolume*weight, cfg.MaxBaseLotAllowed*step)
+		totalQuoteVolume += quoteVolumes[i]
+	}
+
+	// scale all steps down to fit total capital ceiling
+	scale := 1.0
+	if cfg.MaxQuoteCapital > 0 && totalQuoteVolume > cfg.MaxQuoteCapital {
+		scale = cfg.MaxQuoteCapital / totalQuoteVolume
+	}
+
+	for i, step := range cfg.Steps {
+		// round down so total capital ceiling is respected
+		volume := decimal.FloorToTick(quoteVolumes[i]*scale/step, c.baseLotTick)
+
+		if volume > c.maxBaseLotAllowed || volume < c.minBaseLotAllowed {
+			return nil, fmt.Errorf("step: %v volume: %v not in range: %v - %v",
+				step,
+				volume,
+				c.minBaseLotAllowed,
+				c.maxBaseLotAllowed,
+			)
+		}
+
+		// scaled down steps can fall below exchange min notional
+		if volume*step < cfg.QuoteMinVolume {
+			return nil, fmt.Errorf("step: %v volume: %v notional: %v is lower than min notional: %v",
+				step,
+				volume,
+				volume*step,
+				cfg.QuoteMinVolume,
+			)
+		}
+
+		c.volumes[step] = volume
+	}
+
+	return c, nil
+}
+
+func (c *DistanceWeighted) Volume(step float64) (float64, float64, error) {
+	volume, ok := c.volumes[step]
+	if !ok {
+		return 0, 0, fmt.Errorf("step: %v is not part of the grid", step)
+	}
+
+	return volume, 0, nil
+}
@@ -0,0 +1,129 @@
+package compound
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistanceWeightedVolume(t *testing.T) {
+	base := ConfigDistanceWeighted{
+		InitialStepQuoteVolume: 100,
+		MinBaseLotAllowed:      0.001,
+		MaxBaseLotAllowed:      1000,
+		BaseLotTick:            0.001,
+		QuoteMinVolume:         10,
+		Steps:                  []float64{10, 8, 5},
+		Mode:                   DistanceWeightedLinear,
+		Factor:                 1,
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(cfg *ConfigDistanceWeighted)
+		want    []float64
+		wantErr bool
+	}{
+		{
+			name: "linear grows from grid top",
+			edit: func(cfg *ConfigDistanceWeighted) {},
+			want: []float64{10, 25, 60},
+		},
+		{
+			name: "inverse linear grows from grid bottom",
+			edit: func(cfg *ConfigDistanceWeighted) { cfg.Inverse = true },
+			want: []float64{30, 25, 20},
+		},
+		{
+			name: "geometric",
+			edit: func(cfg *ConfigDistanceWeighted) {
+				cfg.Mode = DistanceWeightedGeometric
+				cfg.Factor = 2
+			},
+			want: []float64{10, 25, 80},
+		},
+		{
+			name: "scaled down to max capital",
+			edit: func(cfg *ConfigDistanceWeighted) { cfg.MaxQuoteCapital = 300 },
+			want: []float64{5, 12.5, 30},
+		},
+		{
+			name: "capped at max lot",
+			edit: func(cfg *ConfigDistanceWeighted) { cfg.MaxBaseLotAllowed = 20 },
+			want: []float64{10, 20, 20},
+		},
+		{
+			name: "rounded down to lot tick",
+			edit: func(cfg *ConfigDistanceWeighted) {
+				cfg.Steps = []float64{3}
+				cfg.BaseLotTick = 0.01
+			},
+			want: []float64{33.33},
+		},
+		{
+			name:    "scaled step under min notional",
+			edit:    func(cfg *ConfigDistanceWeighted) { cfg.MaxQuoteCapital = 30 },
+			wantErr: true,
+		},
+		{
+			name:    "scaled step under min lot",
+			edit:    func(cfg *ConfigDistanceWeighted) { cfg.MinBaseLotAllowed = 6; cfg.MaxQuoteCapital = 300 },
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			edit:    func(cfg *ConfigDistanceWeighted) { cfg.Mode = "CUBIC" },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			tt.edit(&cfg)
+
+			c, err := NewDistanceWeighted(&cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var got []float64
+			for _, step := range cfg.Steps {
+				volume, compounded, err := c.Volume(step)
+				if err != nil {
+					t.Fatalf("unexpected error for step: %v, err: %s", step, err)
+				}
+				if compounded != 0 {
+					t.Errorf("expected no compounded volume for step: %v, got: %v", step, compounded)
+				}
+				got = append(got, volume)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected volumes: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDistanceWeightedVolumeUnknownStep(t *testing.T) {
+	c, err := NewDistanceWeighted(&ConfigDistanceWeighted{
+		InitialStepQuoteVolume: 100,
+		MaxBaseLotAllowed:      1000,
+		BaseLotTick:            0.001,
+		Steps:                  []float64{10},
+		Mode:                   DistanceWeightedLinear,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, _, err := c.Volume(9); err == nil {
+		t.Error("expected error for step out of grid")
+	}
+}
@@ -1,6 +1,9 @@
 package compound
 
-import "fmt"
+import (
+	"bwd/pkg/utils/decimal"
+	"fmt"
+)
 
 type ConfigNone struct {
 	InitialStepQuoteVolume float64
@@ -26,8 +29,8 @@ func NewCompoundNone(cfg *ConfigNone) *None {
 }
 
 func (c *None) Volume(step float64) (float64, float64, error) {
-	precision := floatPrecision(c.baseLotTick)
-	volume := toFixed(c.initialStepQuoteVolume/step, precision)
+	precision := decimal.Precision(c.baseLotTick)
+	volume := decimal.ToFixed(c.initialStepQuoteVolume/step, precision)
 
 	if volume > c.maxBaseLotAllowed || volume < c.minBaseLotAllowed {
 		return 0, 0, fmt.Errorf("volume: %v not in range: %v - %v",
@@ -37,5 +40,5 @@ func (c *None) Volume(step float64) (float64, float64, error) {
 		)
 	}
 
-	return toFixed(volume, precision), 0, nil
+	return decimal.ToFixed(volume, precision), 0, nil
 }
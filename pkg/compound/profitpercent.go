@@ -2,6 +2,7 @@ package compound
 
 import (
 	"bwd/pkg/storage"
+	"bwd/pkg/utils/decimal"
 	"fmt"
 	"math"
 )
@@ -13,6 +14,12 @@ type ConfigProfitPercent struct {
 	MinBaseLotAllowed      float64
 	MaxBaseLotAllowed      float64
 	BaseLotTick            float64
+	// ReinvestPercent is the percent of total net income that can be reinvested (0 - 100]
+	ReinvestPercent float64
+	// MaxReinvestQuoteVolume limits quote reinvested on a single trade, 0 means no limit
+	MaxReinvestQuoteVolume float64
+	// MaxStepQuoteVolume limits quote volume of a step, 0 means no limit
+	MaxStepQuoteVolume float64
 }
 
 type ProfitPercent struct {
@@ -22,6 +29,9 @@ type ProfitPercent struct {
 	minBaseLotAllowed      float64
 	maxBaseLotAllowed      float64
 	baseLotTick            float64
+	reinvestPercent        float64
+	maxReinvestQuoteVolume float64
+	maxStepQuoteVolume     float64
 }
 
 func NewProfitPercent(cfg *ConfigProfitPercent) *ProfitPercent {
@@ -32,10 +42,13 @@ func NewProfitPercent(cfg *ConfigProfitPercent) *ProfitPercent {
 		minBaseLotAllowed:      cfg.MinBaseLotAllowed,
 		maxBaseLotAllowed:      cfg.MaxBaseLotAllowed,
 		baseLotTick:            cfg.BaseLotTick,
+		reinvestPercent:        cfg.ReinvestPercent,
+		maxReinvestQuoteVolume: cfg.MaxReinvestQuoteVolume,
+		maxStepQuoteVolume:     cfg.MaxStepQuoteVolume,
 	}
 }
 
-// TODO for moment this compounder will try to compound all profit
+// compounds reinvestPercent of total net income not reserved for withdraw, limited per trade and per step
 // return:
 // total base volume for new trade
 // quote compounded volume
@@ -51,19 +64,34 @@ func (c *ProfitPercent) Volume(step float64) (float64, float64, error) {
 
 	totalVolume = math.Max(totalVolume, latestClosedTrade.BaseVolume)
 
+	// step volume can not exceed cap even if a previous trade was bigger
+	if c.maxStepQuoteVolume > 0 {
+		totalVolume = math.Min(totalVolume, c.maxStepQuoteVolume/step)
+	}
+
 	// get balance and see if we can add compound value
 	latestBalance, err := c.storer.LatestBalanceHistory(c.appID)
 	if err != nil {
 		return 0, 0, err
 	}
-	availableQuoteVolume := latestBalance.TotalNetIncome - latestBalance.TotalReinvested
+	// profit reserved for withdraw can not be reinvested
+	reinvestableIncome := latestBalance.TotalNetIncome - latestBalance.TotalReserved
+	availableQuoteVolume := reinvestableIncome*c.reinvestPercent/100 - latestBalance.TotalReinvested
+
+	if c.maxReinvestQuoteVolume > 0 {
+		availableQuoteVolume = math.Min(availableQuoteVolume, c.maxReinvestQuoteVolume)
+	}
+
+	if c.maxStepQuoteVolume > 0 {
+		availableQuoteVolume = math.Min(availableQuoteVolume, c.maxStepQuoteVolume-totalVolume*step)
+	}
 
-	precision := floatPrecision(c.baseLotTick)
+	precision := decimal.Precision(c.baseLotTick)
 
 	var quoteCompoundedVolume float64
 	if availableQuoteVolume > 0 {
 		baseToCompound := availableQuoteVolume / step
-		if toFixed(baseToCompound, precision) >= c.baseLotTick {
+		if decimal.ToFixed(baseToCompound, precision) >= c.baseLotTick {
 			totalVolume = totalVolume + baseToCompound
 			quoteCompoundedVolume = availableQuoteVolume
 		}
@@ -77,5 +105,5 @@ func (c *ProfitPercent) Volume(step float64) (float64, float64, error) {
 		)
 	}
 
-	return toFixed(totalVolume, precision), quoteCompoundedVolume, nil
+	return decimal.ToFixed(totalVolume, precision), quoteCompoundedVolume, nil
 }
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
@@ -46,6 +47,16 @@ type Binance struct {
 	symbols    map[string]symbol
 	m          sync.Mutex
 	orders     map[int][]Order
+	balances   map[string]Balance
+	reserved   []reservation
+	health     healthTracker
+}
+
+// reservation is balance taken by an order that refreshed balances may not reflect yet
+type reservation struct {
+	asset  string
+	volume float64
+	at     time.Time
 }
 
 type symbol struct {
@@ -84,6 +95,12 @@ func (b *Binance) Start() error {
 		}
 	}
 
+	if err := b.refreshBalances(); err != nil {
+		return err
+	}
+
+	b.health.start()
+
 	go func() {
 		for {
 			select {
@@ -91,7 +108,7 @@ func (b *Binance) Start() error {
 				b.doneSig <- struct{}{}
 				return
 			default:
-				b.run()
+				b.safeRun()
 				<-time.After(b.interval)
 			}
 		}
@@ -192,8 +209,31 @@ func (b *Binance) AddOrder(appID int, order Order) (string, error) {
 	return fmt.Sprintf("%v", resp.OrderID), nil
 }
 
-// TODO implement me (not used at this moment)
+// CancelOrder cancels order on exchange and removes it from cache
 func (b *Binance) CancelOrder(order Order) error {
+	int64ID, err := strconv.ParseInt(order.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse int64 order id: %s, err: %w", order.ID, err)
+	}
+
+	symbol := fmt.Sprintf("%s%s", order.Base, order.Quote)
+	_, err = b.connection.NewCancelOrderService().Symbol(symbol).OrderID(int64ID).Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to cancel order id: %s, err: %w", order.ID, err)
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	for appID, orders := range b.orders {
+		for i, o := range orders {
+			if o.ID == order.ID {
+				b.orders[appID] = append(orders[:i], orders[i+1:]...)
+				return nil
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -252,13 +292,141 @@ func (b *Binance) OrdersDetails(appID int) []Order {
 	return b.orders[appID]
 }
 
+func (b *Binance) Balance(asset string) (Balance, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.balances == nil {
+		return Balance{}, errors.New("balances not synced yet")
+	}
+
+	return b.balance(asset), nil
+}
+
+func (b *Binance) ReserveBalance(asset string, volume float64) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.balances == nil || b.balance(asset).Free < volume {
+		return false
+	}
+
+	b.reserved = append(b.reserved, reservation{asset: asset, volume: volume, at: time.Now()})
+	return true
+}
+
+func (b *Binance) ReleaseBalance(asset string, volume float64) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	// latest matching reservation, older ones may already be dropped by a refresh
+	for i := len(b.reserved) - 1; i >= 0; i-- {
+		if b.reserved[i].asset == asset && b.reserved[i].volume == volume {
+			b.reserved = append(b.reserved[:i], b.reserved[i+1:]...)
+			return
+		}
+	}
+}
+
+// balance returns cached balance with reservations moved from free to locked, lock must be held
+func (b *Binance) balance(asset string) Balance {
+	balance, ok := b.balances[asset]
+	if !ok {
+		balance = Balance{Asset: asset}
+	}
+
+	for _, r := range b.reserved {
+		if r.asset != asset {
+			continue
+		}
+		balance.Free -= r.volume
+		balance.Locked += r.volume
+	}
+
+	if balance.Free < 0 {
+		balance.Free = 0
+	}
+
+	return balance
+}
+
+func (b *Binance) refreshBalances() error {
+	fetchedAt := time.Now()
+	account, err := b.connection.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		return err
+	}
+
+	balances := make(map[string]Balance)
+	for _, exhBalance := range account.Balances {
+		free, err := strconv.ParseFloat(exhBalance.Free, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse free balance for asset: %s, err: %w", exhBalance.Asset, err)
+		}
+
+		locked, err := strconv.ParseFloat(exhBalance.Locked, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse locked balance for asset: %s, err: %w", exhBalance.Asset, err)
+		}
+
+		balances[exhBalance.Asset] = Balance{
+			Asset:  exhBalance.Asset,
+			Free:   free,
+			Locked: locked,
+		}
+	}
+
+	// orders placed while account was fetched may be missing from balances
+	b.m.Lock()
+	b.balances = balances
+	var reserved []reservation
+	for _, r := range b.reserved {
+		if r.at.After(fetchedAt) {
+			reserved = append(reserved, r)
+		}
+	}
+	b.reserved = reserved
+	b.m.Unlock()
+
+	return nil
+}
+
+// safeRun isolates loop panics, connector keeps running on next tick
+func (b *Binance) safeRun() {
+	defer func() {
+		if r := recover(); r != nil {
+			metricRunPanicCount.With(prometheus.Labels{"connector": "binance"}).Inc()
+			b.health.failure()
+			b.logger.WithField("stack", string(debug.Stack())).Errorf("connector Binance run panic: %v", r)
+		}
+	}()
+
+	b.run()
+}
+
+func (b *Binance) Health() Health {
+	return b.health.get()
+}
+
 func (b *Binance) run() {
+	balancesErr := b.refreshBalances()
+	if balancesErr != nil {
+		b.logger.WithError(balancesErr).Warn("could not fetch balances from exchange")
+	}
+
 	exhOpenOrders, err := b.openOrders()
 	if err != nil {
 		b.logger.WithError(err).Warn("could not fetch open exhOrders from exchange")
+		b.health.failure()
 		return
 	}
 
+	if balancesErr != nil {
+		b.health.failure()
+	} else {
+		b.health.success()
+	}
+
 	b.m.Lock()
 	defer b.m.Unlock()
 
@@ -383,6 +551,8 @@ func (b *Binance) castExchangeOrder(order *binance.Order) (Order, error) {
 		status = OrderStatusPartiallyFilled
 	case binance.OrderStatusTypeFilled:
 		status = OrderStatusExecuted
+	case binance.OrderStatusTypeCanceled:
+		status = OrderStatusCanceled
 	default:
 		return Order{}, errors.New(fmt.Sprintf("unknown order side: %s", order.Status))
 	}
@@ -392,20 +562,26 @@ func (b *Binance) castExchangeOrder(order *binance.Order) (Order, error) {
 		return Order{}, err
 	}
 
+	filledVolume, err := strconv.ParseFloat(order.ExecutedQuantity, 64)
+	if err != nil {
+		return Order{}, err
+	}
+
 	pair, ok := b.symbols[order.Symbol]
 	if !ok {
 		return Order{}, fmt.Errorf("pair: %s not founded on binance connector symbols", order.Symbol)
 	}
 
 	return Order{
-		ID:        fmt.Sprintf("%v", order.OrderID),
-		Base:      pair.base,
-		Quote:     pair.quote,
-		OrderType: orderType,
-		Side:      side,
-		Price:     price,
-		Volume:    volume,
-		Status:    status,
+		ID:           fmt.Sprintf("%v", order.OrderID),
+		Base:         pair.base,
+		Quote:        pair.quote,
+		OrderType:    orderType,
+		Side:         side,
+		Price:        price,
+		Volume:       volume,
+		FilledVolume: filledVolume,
+		Status:       status,
 	}, nil
 }
 
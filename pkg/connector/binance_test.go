@@ -0,0 +1,23 @@
+package connector
+
+import "testing"
+
+func TestBinanceReleaseBalance(t *testing.T) {
+	b := &Binance{balances: map[string]Balance{"USDT": {Asset: "USDT", Free: 100}}}
+
+	if !b.ReserveBalance("USDT", 60) {
+		t.Fatal("expected first reservation to fit free balance")
+	}
+	if b.ReserveBalance("USDT", 60) {
+		t.Fatal("expected second reservation to exceed free balance")
+	}
+
+	b.ReleaseBalance("USDT", 60)
+
+	if free := b.balance("USDT").Free; free != 100 {
+		t.Errorf("expected free balance back to: 100, got: %v", free)
+	}
+	if !b.ReserveBalance("USDT", 60) {
+		t.Error("expected released quote to be reservable again")
+	}
+}
@@ -1,5 +1,15 @@
 package connector
 
+import (
+	"bwd/pkg/utils/metrics/exporter"
+	"sync"
+	"time"
+)
+
+var (
+	metricRunPanicCount = exporter.GetCounter("bwd", "connector_run_panic_count", []string{"connector"})
+)
+
 const (
 	OrderTypeMarket = "MARKET"
 	OrderTypeLimit  = "LIMIT"
@@ -10,6 +20,7 @@ const (
 	OrderStatusNew             = "NEW"
 	OrderStatusExecuted        = "EXECUTED"
 	OrderStatusPartiallyFilled = "PARTIALLY_FILLED"
+	OrderStatusCanceled        = "CANCELED"
 	OrderStatusNotFound        = "NOT_FOUND"
 )
 
@@ -21,6 +32,61 @@ type Connector interface {
 	CancelOrder(order Order) error
 	OrderDetails(appID int, order Order) (Order, error)
 	OrdersDetails(appID int) []Order
+	// Balance returns free/locked volume of an asset, cached on connector loop
+	Balance(asset string) (Balance, error)
+	// ReserveBalance moves volume from cached free to locked balance, false when free
+	// balance is lower, apps sharing an account can not spend the same free balance
+	// between two refreshes
+	ReserveBalance(asset string, volume float64) bool
+	// ReleaseBalance gives back a reservation when the order it was made for is not placed
+	ReleaseBalance(asset string, volume float64)
+	// Health returns connector loop sync state
+	Health() Health
+}
+
+// Health describes how connector loop syncs with exchange
+type Health struct {
+	StartedAt         time.Time
+	LastSyncAt        time.Time
+	ConsecutiveErrors int
+	TotalErrors       int
+}
+
+// healthTracker records connector loop results, safe for concurrent use
+type healthTracker struct {
+	m      sync.Mutex
+	health Health
+}
+
+func (h *healthTracker) start() {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.health.StartedAt = time.Now().UTC()
+	h.health.LastSyncAt = h.health.StartedAt
+}
+
+func (h *healthTracker) success() {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.health.LastSyncAt = time.Now().UTC()
+	h.health.ConsecutiveErrors = 0
+}
+
+func (h *healthTracker) failure() {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.health.ConsecutiveErrors++
+	h.health.TotalErrors++
+}
+
+func (h *healthTracker) get() Health {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	return h.health
 }
 
 type PairInfo struct {
@@ -35,6 +101,12 @@ type PairInfo struct {
 	QuoteMinVolume float64
 }
 
+type Balance struct {
+	Asset  string
+	Free   float64
+	Locked float64
+}
+
 type Order struct {
 	ID        string
 	Base      string
@@ -43,5 +115,7 @@ type Order struct {
 	Side      string
 	Price     float64
 	Volume    float64
-	Status    string
+	// FilledVolume is base volume already executed
+	FilledVolume float64
+	Status       string
 }
@@ -3,13 +3,19 @@ package connector
 import (
 	"context"
 	"math/rand"
+	"runtime/debug"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/sirupsen/logrus"
 )
 
+// fakeBalance is the free volume fake connector reports for every asset
+const fakeBalance = 1000000
+
 type FakeConnectorConfig struct {
 	Interval time.Duration
 }
@@ -22,6 +28,7 @@ type FakeConnector struct {
 	doneSig    chan struct{}
 	m          sync.Mutex
 	orders     map[int][]Order
+	health     healthTracker
 }
 
 func NewFakeConnector(cfg *FakeConnectorConfig, logger logrus.FieldLogger) *FakeConnector {
@@ -39,6 +46,8 @@ func NewFakeConnector(cfg *FakeConnectorConfig, logger logrus.FieldLogger) *Fake
 }
 
 func (f *FakeConnector) Start() error {
+	f.health.start()
+
 	go func() {
 		for {
 			select {
@@ -46,7 +55,7 @@ func (f *FakeConnector) Start() error {
 				f.doneSig <- struct{}{}
 				return
 			default:
-				f.run()
+				f.safeRun()
 				<-time.After(f.interval)
 			}
 		}
@@ -97,8 +106,19 @@ func (f *FakeConnector) AddOrder(appID int, order Order) (string, error) {
 	return order.ID, nil
 }
 
-// TODO implement me
 func (f *FakeConnector) CancelOrder(order Order) error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	for appID, orders := range f.orders {
+		for i, o := range orders {
+			if o.ID == order.ID {
+				f.orders[appID] = append(orders[:i], orders[i+1:]...)
+				return nil
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -127,10 +147,42 @@ func (f *FakeConnector) OrdersDetails(appID int) []Order {
 	return f.orders[appID]
 }
 
+func (f *FakeConnector) Balance(asset string) (Balance, error) {
+	return Balance{
+		Asset: asset,
+		Free:  fakeBalance,
+	}, nil
+}
+
+func (f *FakeConnector) ReserveBalance(asset string, volume float64) bool {
+	return volume <= fakeBalance
+}
+
+func (f *FakeConnector) ReleaseBalance(asset string, volume float64) {}
+
+// safeRun isolates loop panics, connector keeps running on next tick
+func (f *FakeConnector) safeRun() {
+	defer func() {
+		if r := recover(); r != nil {
+			metricRunPanicCount.With(prometheus.Labels{"connector": "fake"}).Inc()
+			f.health.failure()
+			f.logger.WithField("stack", string(debug.Stack())).Errorf("connector FakeConnector run panic: %v", r)
+		}
+	}()
+
+	f.run()
+}
+
+func (f *FakeConnector) Health() Health {
+	return f.health.get()
+}
+
 func (f *FakeConnector) run() {
 	f.m.Lock()
 	defer f.m.Unlock()
 
+	f.health.success()
+
 	// random orders executed
 	for appID, _ := range f.orders {
 		for idx, order := range f.orders[appID] {
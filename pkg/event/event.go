@@ -0,0 +1,128 @@
+package event
+
+import (
+	"bwd/pkg/utils/metrics/exporter"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	TypeTradeCreated   = "TRADE_CREATED"
+	TypeOrderPublished = "ORDER_PUBLISHED"
+	TypeBuyExecuted    = "BUY_EXECUTED"
+	TypeSellExecuted   = "SELL_EXECUTED"
+	TypeTradeClosed    = "TRADE_CLOSED"
+	TypeReinvest       = "REINVEST"
+	TypeOrderFailed    = "ORDER_FAILED"
+)
+
+var (
+	metricPublished = exporter.GetCounter("bwd", "event_published_count", []string{"type"})
+	metricDropped   = exporter.GetCounter("bwd", "event_dropped_count", []string{"type"})
+)
+
+// Event is a trade domain event, fields not related to event type are empty
+type Event struct {
+	Type    string  `json:"type"`
+	AppID   int     `json:"app_id"`
+	TradeID int     `json:"trade_id"`
+	Status  string  `json:"status,omitempty"`
+	Side    string  `json:"side,omitempty"`
+	OrderID string  `json:"order_id,omitempty"`
+	Price   float64 `json:"price,omitempty"`
+	Volume  float64 `json:"volume,omitempty"`
+	// Amount is net profit of a closed trade or reinvested volume, expressed in Asset
+	Amount    float64   `json:"amount,omitempty"`
+	Asset     string    `json:"asset,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Publisher publishes events, it should never block the caller
+type Publisher interface {
+	Publish(e Event)
+}
+
+// Bus fans out events to subscribers, slow subscribers lose events instead of
+// slowing down traders
+type Bus struct {
+	m      sync.RWMutex
+	nextID int
+	subs   map[int]*Subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[int]*Subscription),
+	}
+}
+
+// Subscription receives events of selected apps, all apps when none selected
+type Subscription struct {
+	C      <-chan Event
+	c      chan Event
+	id     int
+	appIDs map[int]bool
+	bus    *Bus
+}
+
+func (b *Bus) Publish(e Event) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+
+	labels := prometheus.Labels{"type": e.Type}
+	metricPublished.With(labels).Inc()
+
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	for _, s := range b.subs {
+		if len(s.appIDs) > 0 && !s.appIDs[e.AppID] {
+			continue
+		}
+
+		select {
+		case s.c <- e:
+		default:
+			metricDropped.With(labels).Inc()
+		}
+	}
+}
+
+// Subscribe returns a subscription buffering up to bufferSize events
+func (b *Bus) Subscribe(appIDs []int, bufferSize int) *Subscription {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.nextID++
+	c := make(chan Event, bufferSize)
+	s := &Subscription{
+		C:      c,
+		c:      c,
+		id:     b.nextID,
+		appIDs: make(map[int]bool),
+		bus:    b,
+	}
+	for _, appID := range appIDs {
+		s.appIDs[appID] = true
+	}
+	b.subs[s.id] = s
+
+	return s
+}
+
+// Unsubscribe stops event delivery and closes subscription channel
+func (s *Subscription) Unsubscribe() {
+	s.bus.m.Lock()
+	defer s.bus.m.Unlock()
+
+	if _, ok := s.bus.subs[s.id]; !ok {
+		return
+	}
+
+	delete(s.bus.subs, s.id)
+	close(s.c)
+}
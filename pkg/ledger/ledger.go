@@ -0,0 +1,152 @@
+package ledger
+
+import (
+	"bwd/pkg/storage"
+	"bwd/pkg/trader"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	balanceActionWithdraw = "WITHDRAW"
+)
+
+// Report contains quote amounts of an app profit ledger
+type Report struct {
+	AppID           int
+	TotalNetIncome  float64
+	TotalReinvested float64
+	TotalReserved   float64
+	TotalWithdrawn  float64
+	// Withdrawable is reserved profit that was not withdrawn yet
+	Withdrawable float64
+	// NotAllocated is profit neither reserved for withdraw nor reinvested
+	NotAllocated float64
+}
+
+// Ledger records real withdrawals and reports withdrawable vs reinvested profit
+type Ledger struct {
+	storer storage.Storer
+}
+
+func New(storer storage.Storer) *Ledger {
+	return &Ledger{
+		storer: storer,
+	}
+}
+
+// Report returns ledger report for all apps
+func (l *Ledger) Report() ([]Report, error) {
+	apps, err := l.storer.Apps()
+	if err != nil {
+		return []Report{}, fmt.Errorf("fail fetch apps, err: %w", err)
+	}
+
+	var reports []Report
+	for _, a := range apps {
+		r, err := l.AppReport(a.ID)
+		if err != nil {
+			return []Report{}, err
+		}
+		reports = append(reports, r)
+	}
+
+	return reports, nil
+}
+
+// AppReport returns ledger report for an app
+func (l *Ledger) AppReport(appID int) (Report, error) {
+	latest, err := l.storer.LatestBalanceHistory(appID)
+	if err != nil {
+		return Report{}, fmt.Errorf("fail fetch latest balance history for app: %d, err: %w", appID, err)
+	}
+
+	return Report{
+		AppID:           appID,
+		TotalNetIncome:  latest.TotalNetIncome,
+		TotalReinvested: latest.TotalReinvested,
+		TotalReserved:   latest.TotalReserved,
+		TotalWithdrawn:  latest.TotalWithdrawn,
+		Withdrawable:    latest.TotalReserved - latest.TotalWithdrawn,
+		NotAllocated:    latest.TotalNetIncome - latest.TotalReserved - latest.TotalReinvested,
+	}, nil
+}
+
+// Withdraw records a real withdrawal of reserved profit
+func (l *Ledger) Withdraw(appID int, quoteVolume float64) error {
+	if quoteVolume <= 0 {
+		return errors.New("withdraw volume should be greater than 0")
+	}
+
+	// totals are read and written in one transaction, trader appends rows concurrently
+	err := l.storer.AppendBalanceHistory(appID, func(latest storage.BalanceHistory) (storage.BalanceHistory, bool, error) {
+		withdrawable := latest.TotalReserved - latest.TotalWithdrawn
+		if quoteVolume > withdrawable {
+			return storage.BalanceHistory{}, false, fmt.Errorf("withdraw volume: %v is greater than withdrawable: %v", quoteVolume, withdrawable)
+		}
+
+		balance := latest
+		balance.AppID = appID
+		balance.Action = balanceActionWithdraw
+		balance.QuoteVolume = quoteVolume
+		balance.BaseVolume = 0
+		balance.TotalWithdrawn = latest.TotalWithdrawn + quoteVolume
+		balance.InternalTradeID = 0
+		balance.CreatedAt = time.Now().UTC()
+
+		return balance, true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail withdraw for app: %d, err: %w", appID, err)
+	}
+
+	return nil
+}
+
+// DailyReport aggregates app activity in [from, to), open trades and capital
+// locked are observed when report is generated, capital locked is in quote for
+// BUY_SELL apps and in base for SELL_BUY apps
+func (l *Ledger) DailyReport(app storage.App, from, to time.Time) (storage.DailyReport, error) {
+	appID := app.ID
+
+	lockedAsset := app.Quote
+	if app.Direction == trader.DirectionSellBuy {
+		lockedAsset = app.Base
+	}
+
+	start, err := l.storer.BalanceHistoryBefore(appID, from)
+	if err != nil {
+		return storage.DailyReport{}, fmt.Errorf("fail fetch balance history before %s for app: %d, err: %w", from, appID, err)
+	}
+
+	end, err := l.storer.BalanceHistoryBefore(appID, to)
+	if err != nil {
+		return storage.DailyReport{}, fmt.Errorf("fail fetch balance history before %s for app: %d, err: %w", to, appID, err)
+	}
+
+	closed, err := l.storer.ClosedTradesCount(appID, from, to)
+	if err != nil {
+		return storage.DailyReport{}, fmt.Errorf("fail count closed trades for app: %d, err: %w", appID, err)
+	}
+
+	active, err := l.storer.ActiveTrades(appID)
+	if err != nil {
+		return storage.DailyReport{}, fmt.Errorf("fail fetch active trades for app: %d, err: %w", appID, err)
+	}
+
+	return storage.DailyReport{
+		AppID:              appID,
+		PeriodStart:        from,
+		PeriodEnd:          to,
+		TradesClosed:       closed,
+		NetProfit:          end.TotalNetIncome - start.TotalNetIncome,
+		Reinvested:         end.TotalReinvested - start.TotalReinvested,
+		BaseNetProfit:      end.TotalBaseNetIncome - start.TotalBaseNetIncome,
+		BaseReinvested:     end.TotalBaseReinvested - start.TotalBaseReinvested,
+		OpenTrades:         len(active),
+		CapitalLocked:      trader.LockedCapital(app.Direction, active),
+		CapitalLockedAsset: lockedAsset,
+		CreatedAt:          time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	TypeSlack    = "slack"
+	TypeTelegram = "telegram"
+	TypeWebhook  = "webhook"
+)
+
+// ChannelConfig describes a notification channel, secret fields may reference
+// env vars as $NAME so the config file can be kept without secrets
+type ChannelConfig struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Token  string `json:"token"`
+	ChatID string `json:"chat_id"`
+	Secret string `json:"secret"`
+	Route  Route  `json:"route"`
+}
+
+// LoadChannels reads channels from a json file: {"channels": [...]}
+func LoadChannels(path string) ([]ChannelConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read notify config fail, err: %w", err)
+	}
+
+	var cfg struct {
+		Channels []ChannelConfig `json:"channels"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse notify config fail, err: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i, c := range cfg.Channels {
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("%s-%d", c.Type, i)
+			cfg.Channels[i].Name = c.Name
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("notify channel %s defined twice", c.Name)
+		}
+		names[c.Name] = true
+	}
+
+	return cfg.Channels, nil
+}
+
+// NewNotifier creates the notifier of a channel
+func NewNotifier(c ChannelConfig) (Notifier, error) {
+	url := os.ExpandEnv(c.URL)
+	token := os.ExpandEnv(c.Token)
+	secret := os.ExpandEnv(c.Secret)
+
+	switch c.Type {
+	case TypeSlack:
+		if url == "" {
+			return nil, errors.New("slack channel requires url")
+		}
+		return NewSlack(c.Name, url), nil
+	case TypeTelegram:
+		if token == "" || c.ChatID == "" {
+			return nil, errors.New("telegram channel requires token and chat_id")
+		}
+		return NewTelegram(c.Name, url, token, c.ChatID), nil
+	case TypeWebhook:
+		if url == "" {
+			return nil, errors.New("webhook channel requires url")
+		}
+		return NewWebhook(c.Name, url, secret), nil
+	default:
+		return nil, fmt.Errorf("unknown notify channel type %q", c.Type)
+	}
+}
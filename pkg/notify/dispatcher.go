@@ -0,0 +1,277 @@
+package notify
+
+import (
+	"bwd/pkg/utils/metrics/exporter"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queueSize = 1024
+	// lines merged in one message, extra lines are counted but dropped
+	maxLinesPerMessage = 30
+	// trade closed lines listed in a batch summary
+	maxBatchedTrades = 10
+	// retry backoff doubles after each failed attempt up to this value
+	maxRetryBackoff = time.Minute
+)
+
+var (
+	metricNotifySent       = exporter.GetCounter("bwd", "notify_sent_count", []string{"channel"})
+	metricNotifyFailed     = exporter.GetCounter("bwd", "notify_failed_count", []string{"channel"})
+	metricNotifyDropped    = exporter.GetCounter("bwd", "notify_dropped_count", []string{"channel", "kind"})
+	metricNotifyDeadLetter = exporter.GetCounter("bwd", "notify_dead_letter_count", []string{"channel"})
+)
+
+// DeadLetter is a message not delivered after all attempts
+type DeadLetter struct {
+	Channel   string
+	Message   Message
+	Attempts  int
+	Error     string
+	CreatedAt time.Time
+}
+
+type ConfigDispatcher struct {
+	Notifier Notifier
+	// MinInterval between two messages, notifications waiting are merged
+	MinInterval time.Duration
+	// BatchInterval collects trade closed notifications in one summary
+	BatchInterval time.Duration
+	// MaxAttempts to deliver a message before it is dead lettered, only
+	// network errors, 429 and 5xx answers are retried
+	MaxAttempts int
+	// RetryBackoff before second attempt, doubled for next ones
+	RetryBackoff time.Duration
+	// DeadLetter records messages that keep failing, optional
+	DeadLetter func(dl DeadLetter)
+}
+
+// Dispatcher rate limits, batches and retries notifications of one channel,
+// Notify never blocks callers
+type Dispatcher struct {
+	logger        logrus.FieldLogger
+	notifier      Notifier
+	channel       string
+	minInterval   time.Duration
+	batchInterval time.Duration
+	maxAttempts   int
+	retryBackoff  time.Duration
+	deadLetter    func(dl DeadLetter)
+	queue         chan Notification
+	pending       []string
+	pendingNotifs []Notification
+	dropped       int
+	tradesClosed  []Notification
+	ctx           context.Context
+	cancel        func()
+	doneSig       chan struct{}
+}
+
+func NewDispatcher(cfg *ConfigDispatcher, logger logrus.FieldLogger) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := &Dispatcher{
+		logger:        logger.WithField("module", "notify").WithField("channel", cfg.Notifier.Name()),
+		notifier:      cfg.Notifier,
+		channel:       cfg.Notifier.Name(),
+		minInterval:   cfg.MinInterval,
+		batchInterval: cfg.BatchInterval,
+		maxAttempts:   cfg.MaxAttempts,
+		retryBackoff:  cfg.RetryBackoff,
+		deadLetter:    cfg.DeadLetter,
+		queue:         make(chan Notification, queueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+		doneSig:       make(chan struct{}),
+	}
+
+	if d.minInterval <= 0 {
+		d.minInterval = 2 * time.Second
+	}
+	if d.batchInterval <= 0 {
+		d.batchInterval = time.Minute
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = 5
+	}
+	if d.retryBackoff <= 0 {
+		d.retryBackoff = 2 * time.Second
+	}
+
+	return d
+}
+
+func (d *Dispatcher) Start() {
+	go func() {
+		sendTick := time.NewTicker(d.minInterval)
+		batchTick := time.NewTicker(d.batchInterval)
+		defer sendTick.Stop()
+		defer batchTick.Stop()
+
+		for {
+			select {
+			case <-d.ctx.Done():
+				// flush what is waiting, best effort
+				d.drain()
+				d.flushTradesClosed()
+				d.send()
+				close(d.doneSig)
+				return
+			case n := <-d.queue:
+				d.add(n)
+			case <-batchTick.C:
+				d.flushTradesClosed()
+			case <-sendTick.C:
+				d.send()
+			}
+		}
+	}()
+}
+
+// Stop flushes pending notifications and stops dispatcher
+func (d *Dispatcher) Stop() {
+	d.cancel()
+	<-d.doneSig
+}
+
+// Notify queues a notification, it is dropped when queue is full
+func (d *Dispatcher) Notify(n Notification) {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+
+	select {
+	case d.queue <- n:
+	default:
+		metricNotifyDropped.With(prometheus.Labels{"channel": d.channel, "kind": n.Kind}).Inc()
+	}
+}
+
+func (d *Dispatcher) drain() {
+	for {
+		select {
+		case n := <-d.queue:
+			d.add(n)
+		default:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) add(n Notification) {
+	if n.Kind == KindTradeClosed {
+		d.tradesClosed = append(d.tradesClosed, n)
+		return
+	}
+
+	d.pendingLine(n.Kind, n.Text, n)
+}
+
+func (d *Dispatcher) pendingLine(kind, text string, notifs ...Notification) {
+	if len(d.pending) >= maxLinesPerMessage {
+		d.dropped += len(notifs)
+		metricNotifyDropped.With(prometheus.Labels{"channel": d.channel, "kind": kind}).Add(float64(len(notifs)))
+		return
+	}
+
+	d.pending = append(d.pending, text)
+	d.pendingNotifs = append(d.pendingNotifs, notifs...)
+}
+
+// flushTradesClosed summarises trades closed since previous batch in one line
+func (d *Dispatcher) flushTradesClosed() {
+	if len(d.tradesClosed) == 0 {
+		return
+	}
+
+	lines := []string{fmt.Sprintf("%d trades closed", len(d.tradesClosed))}
+	for i, n := range d.tradesClosed {
+		if i == maxBatchedTrades {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(d.tradesClosed)-maxBatchedTrades))
+			break
+		}
+		lines = append(lines, "• "+n.Text)
+	}
+
+	notifs := d.tradesClosed
+	d.tradesClosed = nil
+	d.pendingLine(KindTradeClosed, strings.Join(lines, "\n"), notifs...)
+}
+
+// send merges pending lines in one message, at most one message per tick
+func (d *Dispatcher) send() {
+	if len(d.pending) == 0 {
+		return
+	}
+
+	m := Message{
+		Text:          strings.Join(d.pending, "\n"),
+		Notifications: d.pendingNotifs,
+	}
+	if d.dropped > 0 {
+		m.Text += fmt.Sprintf("\n(%d notifications dropped by rate limit)", d.dropped)
+	}
+	d.pending = nil
+	d.pendingNotifs = nil
+	d.dropped = 0
+
+	attempts, err := d.deliver(m)
+	if err == nil {
+		metricNotifySent.With(prometheus.Labels{"channel": d.channel}).Inc()
+		return
+	}
+
+	metricNotifyDeadLetter.With(prometheus.Labels{"channel": d.channel}).Inc()
+	d.logger.WithError(err).WithField("attempts", attempts).Error("notification dead lettered")
+
+	if d.deadLetter != nil {
+		d.deadLetter(DeadLetter{
+			Channel:   d.channel,
+			Message:   m,
+			Attempts:  attempts,
+			Error:     err.Error(),
+			CreatedAt: time.Now().UTC(),
+		})
+	}
+}
+
+// deliver retries network errors, rate limits and server errors with backoff,
+// other failures and remaining attempts on stop are dead lettered right away
+func (d *Dispatcher) deliver(m Message) (int, error) {
+	backoff := d.retryBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = d.notifier.Notify(ctx, m)
+		cancel()
+
+		if err == nil {
+			return attempt, nil
+		}
+
+		metricNotifyFailed.With(prometheus.Labels{"channel": d.channel}).Inc()
+		d.logger.WithError(err).WithField("attempt", attempt).Warn("fail send notification")
+
+		if !retryable(err) || attempt >= d.maxAttempts {
+			return attempt, err
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return attempt, err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeNotifier records messages and fails with errs in order before succeeding
+type fakeNotifier struct {
+	errs     []error
+	calls    int
+	messages []Message
+}
+
+func (f *fakeNotifier) Name() string {
+	return "fake"
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, m Message) error {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+
+	f.messages = append(f.messages, m)
+	return nil
+}
+
+func newTestDispatcher(n Notifier, deadLetter func(dl DeadLetter)) *Dispatcher {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	return NewDispatcher(&ConfigDispatcher{
+		Notifier:     n,
+		MaxAttempts:  3,
+		RetryBackoff: time.Millisecond,
+		DeadLetter:   deadLetter,
+	}, logger)
+}
+
+func tradesClosed(n int) []Notification {
+	var notifs []Notification
+	for i := 1; i <= n; i++ {
+		notifs = append(notifs, Notification{Kind: KindTradeClosed, AppID: 1, Text: fmt.Sprintf("trade %d closed", i)})
+	}
+	return notifs
+}
+
+func TestDispatcherBatching(t *testing.T) {
+	var overflow []Notification
+	for i := 0; i < maxLinesPerMessage+2; i++ {
+		overflow = append(overflow, Notification{Kind: KindAppFailed, Text: fmt.Sprintf("app %d failed", i)})
+	}
+
+	tests := []struct {
+		name   string
+		notifs []Notification
+		// wantText is the single message expected, empty when nothing is sent
+		wantText   string
+		wantNotifs int
+	}{
+		{
+			name:     "nothing pending",
+			wantText: "",
+		},
+		{
+			name: "lines merged in one message",
+			notifs: []Notification{
+				{Kind: KindAppStarted, AppID: 1, Text: "app 1 started"},
+				{Kind: KindAppStopped, AppID: 2, Text: "app 2 stopped"},
+			},
+			wantText:   "app 1 started\napp 2 stopped",
+			wantNotifs: 2,
+		},
+		{
+			name:       "trades closed summarised",
+			notifs:     append([]Notification{{Kind: KindAppStarted, AppID: 1, Text: "app 1 started"}}, tradesClosed(2)...),
+			wantText:   "app 1 started\n2 trades closed\n• trade 1 closed\n• trade 2 closed",
+			wantNotifs: 3,
+		},
+		{
+			name:       "trades closed over batch limit counted",
+			notifs:     tradesClosed(maxBatchedTrades + 2),
+			wantText:   "12 trades closed\n• trade 1 closed",
+			wantNotifs: maxBatchedTrades + 2,
+		},
+		{
+			name:       "lines over message limit dropped",
+			notifs:     overflow,
+			wantText:   "(2 notifications dropped by rate limit)",
+			wantNotifs: maxLinesPerMessage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &fakeNotifier{}
+			d := newTestDispatcher(n, nil)
+
+			for _, notif := range tt.notifs {
+				d.add(notif)
+			}
+			d.flushTradesClosed()
+			d.send()
+
+			if tt.wantText == "" {
+				if len(n.messages) != 0 {
+					t.Fatalf("expected no message, got: %d", len(n.messages))
+				}
+				return
+			}
+
+			if len(n.messages) != 1 {
+				t.Fatalf("expected one message, got: %d", len(n.messages))
+			}
+			m := n.messages[0]
+			if !strings.Contains(m.Text, tt.wantText) {
+				t.Errorf("expected text containing: %q, got: %q", tt.wantText, m.Text)
+			}
+			if len(m.Notifications) != tt.wantNotifs {
+				t.Errorf("expected notifications: %d, got: %d", tt.wantNotifs, len(m.Notifications))
+			}
+		})
+	}
+}
+
+func TestDispatcherTradesClosedOverBatchLimit(t *testing.T) {
+	n := &fakeNotifier{}
+	d := newTestDispatcher(n, nil)
+
+	for _, notif := range tradesClosed(maxBatchedTrades + 2) {
+		d.add(notif)
+	}
+	d.flushTradesClosed()
+	d.send()
+
+	text := n.messages[0].Text
+	if !strings.Contains(text, "... and 2 more") {
+		t.Errorf("expected remaining trades count, got: %q", text)
+	}
+	if strings.Contains(text, fmt.Sprintf("trade %d closed", maxBatchedTrades+1)) {
+		t.Errorf("expected trades over batch limit not listed, got: %q", text)
+	}
+}
+
+func TestDispatcherDeliver(t *testing.T) {
+	tests := []struct {
+		name           string
+		errs           []error
+		wantCalls      int
+		wantDeadLetter bool
+	}{
+		{
+			name:      "sent first attempt",
+			wantCalls: 1,
+		},
+		{
+			name:      "server errors retried",
+			errs:      []error{&StatusError{Channel: "fake", StatusCode: 502}, &StatusError{Channel: "fake", StatusCode: 429}},
+			wantCalls: 3,
+		},
+		{
+			name:           "network errors dead lettered after max attempts",
+			errs:           []error{errors.New("timeout"), errors.New("timeout"), errors.New("timeout")},
+			wantCalls:      3,
+			wantDeadLetter: true,
+		},
+		{
+			name:           "client error dead lettered without retry",
+			errs:           []error{&StatusError{Channel: "fake", StatusCode: 400}},
+			wantCalls:      1,
+			wantDeadLetter: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadLetters []DeadLetter
+			n := &fakeNotifier{errs: tt.errs}
+			d := newTestDispatcher(n, func(dl DeadLetter) { deadLetters = append(deadLetters, dl) })
+
+			d.add(Notification{Kind: KindAppFailed, AppID: 1, Text: "app 1 failed"})
+			d.send()
+
+			if n.calls != tt.wantCalls {
+				t.Errorf("expected calls: %d, got: %d", tt.wantCalls, n.calls)
+			}
+
+			if !tt.wantDeadLetter {
+				if len(deadLetters) != 0 {
+					t.Errorf("expected no dead letter, got: %+v", deadLetters)
+				}
+				return
+			}
+
+			if len(deadLetters) != 1 {
+				t.Fatalf("expected one dead letter, got: %d", len(deadLetters))
+			}
+			if deadLetters[0].Attempts != tt.wantCalls {
+				t.Errorf("expected dead letter attempts: %d, got: %d", tt.wantCalls, deadLetters[0].Attempts)
+			}
+		})
+	}
+}
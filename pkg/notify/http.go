@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// StatusError is returned when a channel answers with a non 2xx status
+type StatusError struct {
+	Channel    string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s post fail, status: %d", e.Channel, e.StatusCode)
+}
+
+// retryable reports if a failed delivery may succeed later, network errors,
+// rate limits and server errors are retried, other statuses will not change
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	return true
+}
+
+// post sends a json body, channel urls carry secrets so they are kept out of errors
+func post(ctx context.Context, client *http.Client, channel, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s create request fail", channel)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if uerr, ok := err.(interface{ Unwrap() error }); ok {
+			err = uerr.Unwrap()
+		}
+		return fmt.Errorf("%s post fail, err: %s", channel, err.Error())
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{Channel: channel, StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
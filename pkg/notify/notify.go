@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	KindAppStarted    = "APP_STARTED"
+	KindAppStopped    = "APP_STOPPED"
+	KindAppFailed     = "APP_FAILED"
+	KindTradeClosed   = "TRADE_CLOSED"
+	KindOrderErrors   = "ORDER_ERRORS"
+	KindConnectorDown = "CONNECTOR_DOWN"
+	KindDailyReport   = "DAILY_REPORT"
+)
+
+// Notification is a human readable message about bwd activity
+type Notification struct {
+	Kind      string    `json:"kind"`
+	AppID     int       `json:"app_id"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Message is what a notifier delivers, Text merges notifications for chat channels
+type Message struct {
+	Text          string
+	Notifications []Notification
+}
+
+// Notifier delivers a message to one channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, m Message) error
+}
+
+// Route selects notifications sent to a channel, empty fields match everything
+type Route struct {
+	Kinds  []string `json:"kinds"`
+	AppIDs []int    `json:"apps"`
+}
+
+func (r Route) Match(n Notification) bool {
+	if len(r.Kinds) > 0 && !containsString(r.Kinds, n.Kind) {
+		return false
+	}
+
+	// bwd wide notifications have no app and go to every channel of the kind
+	if len(r.AppIDs) > 0 && n.AppID != 0 && !containsInt(r.AppIDs, n.AppID) {
+		return false
+	}
+
+	return true
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, v int) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRouteMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		route Route
+		n     Notification
+		want  bool
+	}{
+		{name: "empty route matches all", route: Route{}, n: Notification{Kind: KindAppFailed, AppID: 3}, want: true},
+		{name: "kind listed", route: Route{Kinds: []string{KindAppFailed}}, n: Notification{Kind: KindAppFailed, AppID: 3}, want: true},
+		{name: "kind not listed", route: Route{Kinds: []string{KindAppFailed}}, n: Notification{Kind: KindTradeClosed, AppID: 3}, want: false},
+		{name: "app listed", route: Route{AppIDs: []int{1, 3}}, n: Notification{Kind: KindTradeClosed, AppID: 3}, want: true},
+		{name: "app not listed", route: Route{AppIDs: []int{1}}, n: Notification{Kind: KindTradeClosed, AppID: 3}, want: false},
+		{name: "bwd wide notification ignores apps", route: Route{AppIDs: []int{1}}, n: Notification{Kind: KindConnectorDown}, want: true},
+		{name: "kind and app listed", route: Route{Kinds: []string{KindTradeClosed}, AppIDs: []int{3}}, n: Notification{Kind: KindTradeClosed, AppID: 3}, want: true},
+		{name: "app listed kind not listed", route: Route{Kinds: []string{KindAppFailed}, AppIDs: []int{3}}, n: Notification{Kind: KindTradeClosed, AppID: 3}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.route.Match(tt.n); got != tt.want {
+				t.Errorf("expected: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"text":"hi"}`)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		want      string
+	}{
+		{name: "signature", secret: "secret", timestamp: "1700000000", want: "3ad1ab8e3e2036926574b48bb349a6926291abb28309d44d18066d4f51de2112"},
+		{name: "other secret", secret: "other", timestamp: "1700000000", want: "32aa139ae9aec25e91a0a91b931625b5fd2f3f3c9c1849bbb26247242342794e"},
+		{name: "other timestamp", secret: "secret", timestamp: "1700000001", want: "22e76b096693aecb1cc18e29a02a585ca32991c0031259cbd7d30ef2d470587a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign([]byte(tt.secret), tt.timestamp, body); got != tt.want {
+				t.Errorf("expected: %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "network error", err: errors.New("slack post fail, err: connection refused"), want: true},
+		{name: "rate limited", err: &StatusError{Channel: "slack", StatusCode: 429}, want: true},
+		{name: "server error", err: &StatusError{Channel: "slack", StatusCode: 503}, want: true},
+		{name: "wrapped server error", err: fmt.Errorf("deliver, err: %w", &StatusError{Channel: "slack", StatusCode: 500}), want: true},
+		{name: "bad request", err: &StatusError{Channel: "slack", StatusCode: 400}, want: false},
+		{name: "not found", err: &StatusError{Channel: "webhook", StatusCode: 404}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("expected: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
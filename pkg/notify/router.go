@@ -0,0 +1,51 @@
+package notify
+
+import "time"
+
+// Router sends each notification to the dispatchers whose route matches it
+type Router struct {
+	routes []routed
+}
+
+type routed struct {
+	route      Route
+	dispatcher *Dispatcher
+}
+
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Add registers a channel dispatcher, it must be called before Start
+func (r *Router) Add(route Route, d *Dispatcher) {
+	r.routes = append(r.routes, routed{route: route, dispatcher: d})
+}
+
+func (r *Router) Len() int {
+	return len(r.routes)
+}
+
+func (r *Router) Start() {
+	for _, rt := range r.routes {
+		rt.dispatcher.Start()
+	}
+}
+
+// Stop flushes every channel
+func (r *Router) Stop() {
+	for _, rt := range r.routes {
+		rt.dispatcher.Stop()
+	}
+}
+
+func (r *Router) Notify(n Notification) {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+
+	for _, rt := range r.routes {
+		if rt.route.Match(n) {
+			rt.dispatcher.Notify(n)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Slack posts messages to a Slack incoming webhook
+type Slack struct {
+	name   string
+	hook   string
+	client *http.Client
+}
+
+func NewSlack(name, hook string) *Slack {
+	return &Slack{
+		name:   name,
+		hook:   hook,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *Slack) Name() string {
+	return s.name
+}
+
+func (s *Slack) Notify(ctx context.Context, m Message) error {
+	body, err := json.Marshal(map[string]string{"text": m.Text})
+	if err != nil {
+		return err
+	}
+
+	return post(ctx, s.client, "slack", s.hook, body, nil)
+}
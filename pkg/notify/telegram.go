@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"bwd/pkg/utils/text"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	telegramAPIURL = "https://api.telegram.org"
+	// telegram rejects longer messages
+	telegramMaxText = 4096
+)
+
+// Telegram sends messages to a chat through a bot
+type Telegram struct {
+	name   string
+	url    string
+	chatID string
+	client *http.Client
+}
+
+// NewTelegram creates a bot notifier, apiURL defaults to telegram bot api
+func NewTelegram(name, apiURL, token, chatID string) *Telegram {
+	if apiURL == "" {
+		apiURL = telegramAPIURL
+	}
+
+	return &Telegram{
+		name:   name,
+		url:    strings.TrimRight(apiURL, "/") + "/bot" + token + "/sendMessage",
+		chatID: chatID,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *Telegram) Name() string {
+	return t.name
+}
+
+func (t *Telegram) Notify(ctx context.Context, m Message) error {
+	msg := m.Text
+	if utf8.RuneCountInString(msg) > telegramMaxText {
+		msg = text.Truncate(msg, telegramMaxText-3) + "..."
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  t.chatID,
+		"text":                     msg,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return post(ctx, t.client, "telegram", t.url, body, nil)
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureHeader holds hex hmac-sha256 of "<timestamp>.<body>" with webhook secret
+	SignatureHeader = "X-Bwd-Signature"
+	TimestampHeader = "X-Bwd-Timestamp"
+)
+
+// Webhook posts notifications as json to an arbitrary url
+type Webhook struct {
+	name   string
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+type webhookPayload struct {
+	Text          string         `json:"text"`
+	Notifications []Notification `json:"notifications"`
+	SentAt        time.Time      `json:"sent_at"`
+}
+
+// NewWebhook creates a json webhook notifier, requests are signed when secret is set
+func NewWebhook(name, url, secret string) *Webhook {
+	return &Webhook{
+		name:   name,
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+func (w *Webhook) Notify(ctx context.Context, m Message) error {
+	now := time.Now().UTC()
+	body, err := json.Marshal(webhookPayload{
+		Text:          m.Text,
+		Notifications: m.Notifications,
+		SentAt:        now,
+	})
+	if err != nil {
+		return err
+	}
+
+	var headers map[string]string
+	if len(w.secret) > 0 {
+		ts := strconv.FormatInt(now.Unix(), 10)
+		headers = map[string]string{
+			TimestampHeader: ts,
+			SignatureHeader: "sha256=" + Sign(w.secret, ts, body),
+		}
+	}
+
+	return post(ctx, w.client, "webhook", w.url, body, headers)
+}
+
+// Sign returns hex hmac-sha256 of timestamp and body, receivers recompute it to verify
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,104 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// KeySize is master key size in bytes, AES-256
+const KeySize = 32
+
+// Cipher encrypts secrets stored at rest with master key
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func New(masterKey []byte) (*Cipher, error) {
+	if len(masterKey) != KeySize {
+		return nil, fmt.Errorf("master key should be %d bytes, got: %d", KeySize, len(masterKey))
+	}
+
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt returns base64 of nonce followed by sealed plaintext
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *Cipher) Decrypt(encrypted string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("fail decode secret, err: %w", err)
+	}
+
+	if len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("secret too short")
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		// do not wrap, error must not carry anything about the secret
+		return "", errors.New("fail decrypt secret, wrong master key or corrupted data")
+	}
+
+	return string(plaintext), nil
+}
+
+// LoadMasterKey reads base64 master key from value, or from keyFile when value is empty
+func LoadMasterKey(value, keyFile string) ([]byte, error) {
+	if value == "" && keyFile != "" {
+		content, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("fail read master key file, err: %w", err)
+		}
+		value = string(content)
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, errors.New("master key not configured")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("master key is not valid base64")
+	}
+
+	return key, nil
+}
+
+// GenerateMasterKey returns a new random base64 master key
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
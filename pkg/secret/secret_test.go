@@ -0,0 +1,103 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestCipherRoundTrip(t *testing.T) {
+	c, err := New(bytes.Repeat([]byte{1}, KeySize))
+	if err != nil {
+		t.Fatalf("fail create cipher, err: %s", err)
+	}
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{name: "empty", plaintext: ""},
+		{name: "api key", plaintext: "vmPUZE6mv9SD5VNHk4HlWFsOr6aKE2zvsw0MuIgwCIPy6utIco14y7Ju91duEh8A"},
+		{name: "multi byte", plaintext: "clé secrète ✓"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := c.Encrypt(tt.plaintext)
+			if err != nil {
+				t.Fatalf("fail encrypt, err: %s", err)
+			}
+			if tt.plaintext != "" && encrypted == tt.plaintext {
+				t.Fatal("encrypted secret equals plaintext")
+			}
+
+			decrypted, err := c.Decrypt(encrypted)
+			if err != nil {
+				t.Fatalf("fail decrypt, err: %s", err)
+			}
+			if decrypted != tt.plaintext {
+				t.Errorf("expected: %q, got: %q", tt.plaintext, decrypted)
+			}
+		})
+	}
+}
+
+func TestCipherDecryptRejects(t *testing.T) {
+	c, err := New(bytes.Repeat([]byte{1}, KeySize))
+	if err != nil {
+		t.Fatalf("fail create cipher, err: %s", err)
+	}
+	other, err := New(bytes.Repeat([]byte{2}, KeySize))
+	if err != nil {
+		t.Fatalf("fail create cipher, err: %s", err)
+	}
+
+	encrypted, err := c.Encrypt("api secret")
+	if err != nil {
+		t.Fatalf("fail encrypt, err: %s", err)
+	}
+
+	sealed, _ := base64.StdEncoding.DecodeString(encrypted)
+	sealed[len(sealed)-1] ^= 0xff
+	tampered := base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name      string
+		cipher    *Cipher
+		encrypted string
+	}{
+		{name: "tampered ciphertext", cipher: c, encrypted: tampered},
+		{name: "wrong master key", cipher: other, encrypted: encrypted},
+		{name: "not base64", cipher: c, encrypted: "not base64!"},
+		{name: "shorter than nonce", cipher: c, encrypted: base64.StdEncoding.EncodeToString([]byte("short"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if plaintext, err := tt.cipher.Decrypt(tt.encrypted); err == nil {
+				t.Errorf("expected error, got plaintext: %q", plaintext)
+			}
+		})
+	}
+}
+
+func TestNewKeySize(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		wantErr bool
+	}{
+		{name: "aes 256 key", size: KeySize},
+		{name: "short key", size: 16, wantErr: true},
+		{name: "empty key", size: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(make([]byte, tt.size))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
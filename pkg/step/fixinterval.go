@@ -1,16 +1,29 @@
 package step
 
 import (
+	"bwd/pkg/utils/decimal"
 	"encoding/json"
+	"fmt"
 	"strconv"
 )
 
+const (
+	// maxStepsNumber is the upper limit of steps a grid can have
+	maxStepsNumber = 1000
+)
+
 type ConfigStepsFixInterval struct {
 	MinPriceAllowed float64
 	MaxPriceAllowed float64
 	PriceTick       float64
 	QuoteMinVolume  float64
-	AppSettings     string
+	BaseLotMin      float64
+	BaseLotMax      float64
+	BaseLotTick     float64
+	StepQuoteVolume float64
+	// Inverse grids close trades one interval below the step (sell then buy back)
+	Inverse     bool
+	AppSettings string
 }
 
 type StepsFixInterval struct {
@@ -18,11 +31,17 @@ type StepsFixInterval struct {
 	minPriceAllowed float64
 	maxPriceAllowed float64
 	priceTick       float64
+	quoteMinVolume  float64
+	baseLotMin      float64
+	baseLotMax      float64
+	baseLotTick     float64
 	// app settings
-	appSettings  string
-	gridMinPrice float64
-	gridMaxPrice float64
-	gridInterval float64
+	stepQuoteVolume float64
+	inverse         bool
+	appSettings     string
+	gridMinPrice    float64
+	gridMaxPrice    float64
+	gridInterval    float64
 }
 
 func NewStepsFixInterval(cfg *ConfigStepsFixInterval) (*StepsFixInterval, error) {
@@ -30,6 +49,12 @@ func NewStepsFixInterval(cfg *ConfigStepsFixInterval) (*StepsFixInterval, error)
 		minPriceAllowed: cfg.MinPriceAllowed,
 		maxPriceAllowed: cfg.MaxPriceAllowed,
 		priceTick:       cfg.PriceTick,
+		quoteMinVolume:  cfg.QuoteMinVolume,
+		baseLotMin:      cfg.BaseLotMin,
+		baseLotMax:      cfg.BaseLotMax,
+		baseLotTick:     cfg.BaseLotTick,
+		stepQuoteVolume: cfg.StepQuoteVolume,
+		inverse:         cfg.Inverse,
 		appSettings:     cfg.AppSettings,
 		gridMinPrice:    0,
 		gridMaxPrice:    0,
@@ -46,11 +71,11 @@ func NewStepsFixInterval(cfg *ConfigStepsFixInterval) (*StepsFixInterval, error)
 func (s *StepsFixInterval) Steps() []float64 {
 	var steps []float64
 
-	precision := floatPrecision(s.priceTick)
-	m := s.gridMinPrice
+	precision := decimal.Precision(s.priceTick)
+	m := s.gridMaxPrice
 
-	for toFixed(m, precision) >= s.gridMaxPrice {
-		steps = append(steps, toFixed(m, precision))
+	for decimal.ToFixed(m, precision) >= s.gridMinPrice {
+		steps = append(steps, decimal.ToFixed(m, precision))
 		m = m - s.gridInterval
 	}
 
@@ -58,8 +83,103 @@ func (s *StepsFixInterval) Steps() []float64 {
 }
 
 func (s *StepsFixInterval) ClosePrice(step float64) float64 {
-	precision := floatPrecision(s.priceTick)
-	return toFixed(step+s.gridInterval, precision)
+	precision := decimal.Precision(s.priceTick)
+	if s.inverse {
+		return decimal.ToFixed(step-s.gridInterval, precision)
+	}
+	return decimal.ToFixed(step+s.gridInterval, precision)
+}
+
+// Validate checks grid against exchange price tick, lot and notional rules
+func (s *StepsFixInterval) Validate() Violations {
+	var violations Violations
+
+	if s.gridInterval <= 0 {
+		violations = append(violations, Violation{Reason: "interval should be greater than 0"})
+		// can not generate steps without a valid interval
+		return violations
+	}
+
+	if !decimal.IsMultiple(s.gridInterval, s.priceTick) {
+		violations = append(violations, Violation{
+			Reason: fmt.Sprintf("interval: %v is not a multiple of price tick: %v", s.gridInterval, s.priceTick),
+		})
+	}
+
+	if s.gridMinPrice < s.minPriceAllowed {
+		violations = append(violations, Violation{
+			Reason: fmt.Sprintf("grid min price: %v is lower than exchange min price: %v", s.gridMinPrice, s.minPriceAllowed),
+		})
+	}
+
+	if s.gridMaxPrice > s.maxPriceAllowed {
+		violations = append(violations, Violation{
+			Reason: fmt.Sprintf("grid max price: %v is greater than exchange max price: %v", s.gridMaxPrice, s.maxPriceAllowed),
+		})
+	}
+
+	stepsNumber := int((s.gridMaxPrice-s.gridMinPrice)/s.gridInterval) + 1
+	if stepsNumber < 1 || stepsNumber > maxStepsNumber {
+		violations = append(violations, Violation{
+			Reason: fmt.Sprintf("grid has %d steps, allowed range: 1 - %d", stepsNumber, maxStepsNumber),
+		})
+		// do not validate each step on an insane grid
+		return violations
+	}
+
+	lotPrecision := decimal.Precision(s.baseLotTick)
+	for _, step := range s.Steps() {
+		if step <= 0 {
+			violations = append(violations, Violation{Step: step, Reason: "price should be greater than 0"})
+			continue
+		}
+
+		if !decimal.IsMultiple(step, s.priceTick) {
+			violations = append(violations, Violation{
+				Step:   step,
+				Reason: fmt.Sprintf("price is not a multiple of price tick: %v", s.priceTick),
+			})
+		}
+
+		closePrice := s.ClosePrice(step)
+		if !decimal.IsMultiple(closePrice, s.priceTick) {
+			violations = append(violations, Violation{
+				Step:   step,
+				Reason: fmt.Sprintf("close price: %v is not a multiple of price tick: %v", closePrice, s.priceTick),
+			})
+		}
+
+		if closePrice < s.minPriceAllowed || closePrice <= 0 {
+			violations = append(violations, Violation{
+				Step:   step,
+				Reason: fmt.Sprintf("close price: %v is lower than exchange min price: %v", closePrice, s.minPriceAllowed),
+			})
+		}
+
+		if closePrice > s.maxPriceAllowed {
+			violations = append(violations, Violation{
+				Step:   step,
+				Reason: fmt.Sprintf("close price: %v is greater than exchange max price: %v", closePrice, s.maxPriceAllowed),
+			})
+		}
+
+		baseVolume := decimal.ToFixed(s.stepQuoteVolume/step, lotPrecision)
+		if baseVolume < s.baseLotMin || baseVolume > s.baseLotMax {
+			violations = append(violations, Violation{
+				Step:   step,
+				Reason: fmt.Sprintf("base volume: %v not in exchange lot range: %v - %v", baseVolume, s.baseLotMin, s.baseLotMax),
+			})
+		}
+
+		if quoteVolume := baseVolume * step; quoteVolume < s.quoteMinVolume {
+			violations = append(violations, Violation{
+				Step:   step,
+				Reason: fmt.Sprintf("quote volume: %v after lot rounding is lower than exchange min volume: %v", quoteVolume, s.quoteMinVolume),
+			})
+		}
+	}
+
+	return violations
 }
 
 func (s *StepsFixInterval) parseSettings() error {
@@ -77,13 +197,13 @@ func (s *StepsFixInterval) parseSettings() error {
 	if err != nil {
 		return err
 	}
-	s.gridMaxPrice = minFloat
+	s.gridMinPrice = minFloat
 
 	maxFloat, err := strconv.ParseFloat(tmp.Max, 64)
 	if err != nil {
 		return err
 	}
-	s.gridMinPrice = maxFloat
+	s.gridMaxPrice = maxFloat
 
 	intervalFloat, err := strconv.ParseFloat(tmp.Interval, 64)
 	if err != nil {
@@ -0,0 +1,130 @@
+package step
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestStepper(t *testing.T, settings string, quoteVolume float64) *StepsFixInterval {
+	t.Helper()
+
+	s, err := NewStepsFixInterval(&ConfigStepsFixInterval{
+		MinPriceAllowed: 0.01,
+		MaxPriceAllowed: 100000,
+		PriceTick:       0.01,
+		QuoteMinVolume:  10,
+		BaseLotMin:      0.001,
+		BaseLotMax:      1000,
+		BaseLotTick:     0.001,
+		StepQuoteVolume: quoteVolume,
+		AppSettings:     settings,
+	})
+	if err != nil {
+		t.Fatalf("fail create stepper, err: %s", err)
+	}
+
+	return s
+}
+
+func TestStepsFixIntervalValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    string
+		quoteVolume float64
+		// reasons expected, each one should be part of a violation
+		reasons []string
+	}{
+		{
+			name:        "valid grid",
+			settings:    `{"min":"100","max":"110","interval":"1"}`,
+			quoteVolume: 20,
+		},
+		{
+			name:        "zero interval",
+			settings:    `{"min":"100","max":"110","interval":"0"}`,
+			quoteVolume: 20,
+			reasons:     []string{"interval should be greater than 0"},
+		},
+		{
+			name:        "interval not multiple of tick",
+			settings:    `{"min":"100","max":"110","interval":"1.005"}`,
+			quoteVolume: 20,
+			reasons:     []string{"is not a multiple of price tick"},
+		},
+		{
+			name:        "grid above exchange max price",
+			settings:    `{"min":"100","max":"200000","interval":"100000"}`,
+			quoteVolume: 200000,
+			reasons:     []string{"grid max price: 200000 is greater than exchange max price: 100000"},
+		},
+		{
+			name:        "grid below exchange min price",
+			settings:    `{"min":"0","max":"10","interval":"1"}`,
+			quoteVolume: 20,
+			reasons:     []string{"grid min price: 0 is lower than exchange min price: 0.01"},
+		},
+		{
+			name:        "too many steps",
+			settings:    `{"min":"1","max":"100","interval":"0.01"}`,
+			quoteVolume: 20,
+			reasons:     []string{"allowed range: 1 - 1000"},
+		},
+		{
+			name:        "quote volume under exchange min notional",
+			settings:    `{"min":"100","max":"110","interval":"1"}`,
+			quoteVolume: 5,
+			reasons:     []string{"lower than exchange min volume"},
+		},
+		{
+			name:        "base volume under lot min",
+			settings:    `{"min":"100","max":"110","interval":"1"}`,
+			quoteVolume: 0.01,
+			reasons:     []string{"not in exchange lot range"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := newTestStepper(t, tt.settings, tt.quoteVolume).Validate()
+
+			if len(tt.reasons) == 0 && len(violations) > 0 {
+				t.Fatalf("expected no violation, got: %s", violations)
+			}
+
+			for _, reason := range tt.reasons {
+				if !strings.Contains(violations.Error(), reason) {
+					t.Errorf("expected violation: %q, got: %q", reason, violations.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestStepsFixIntervalClosePrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		inverse bool
+		step    float64
+		want    float64
+	}{
+		{name: "buy sell closes above", step: 100, want: 100.5},
+		{name: "sell buy closes below", inverse: true, step: 100, want: 99.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewStepsFixInterval(&ConfigStepsFixInterval{
+				PriceTick:   0.01,
+				Inverse:     tt.inverse,
+				AppSettings: `{"min":"100","max":"110","interval":"0.5"}`,
+			})
+			if err != nil {
+				t.Fatalf("fail create stepper, err: %s", err)
+			}
+
+			if got := s.ClosePrice(tt.step); got != tt.want {
+				t.Errorf("expected close price: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
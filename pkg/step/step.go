@@ -1,7 +1,7 @@
 package step
 
 import (
-	"math"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -9,27 +9,31 @@ import (
 type Stepper interface {
 	Steps() []float64
 	ClosePrice(step float64) float64
+	// Validate returns all violations of exchange rules, empty when stepper is usable
+	Validate() Violations
 }
 
-func round(num float64) int {
-	return int(num + math.Copysign(0.5, num))
+// Violation describes a single stepper misconfiguration, step is 0 when violation is not step related
+type Violation struct {
+	Step   float64
+	Reason string
 }
 
-func toFixed(num float64, precision int) float64 {
-	output := math.Pow(10, float64(precision))
-	return float64(round(num*output)) / output
-}
-
-// floatPrecision returns the number of digits after .
-func floatPrecision(f float64) int {
-	strVal := strconv.FormatFloat(f, 'f', -1, 64)
-	split := strings.Split(strVal, ".")
+// Violations is a list of stepper misconfigurations that can be returned as error
+type Violations []Violation
 
-	// digits after floating point
-	daf := 0
-	if len(split) > 1 {
-		daf = len(split[1])
+func (v Violations) Error() string {
+	var reasons []string
+	for _, violation := range v {
+		if violation.Step == 0 {
+			reasons = append(reasons, violation.Reason)
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("step %s: %s",
+			strconv.FormatFloat(violation.Step, 'f', -1, 64),
+			violation.Reason,
+		))
 	}
 
-	return daf
+	return strings.Join(reasons, "; ")
 }
@@ -2,9 +2,11 @@ package storage
 
 import (
 	"bwd/pkg/utils/metrics/exporter"
+	"bwd/pkg/utils/text"
 	"database/sql"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -41,19 +43,31 @@ func NewMysql(connString string) (*Mysql, error) {
 		return nil, err
 	}
 
+	if err = instance.migrateSchema(); err != nil {
+		return nil, err
+	}
+
 	return &instance, nil
 }
 
+// Ping checks database connection
+func (s *Mysql) Ping() error {
+	return s.db.Ping()
+}
+
 func (s *Mysql) Apps() ([]App, error) {
 	rows, err := s.db.Query(`
         SELECT
             app_id,
             run_interval,
             exchange,
+            account_id,
             market_order_fees,
             limit_order_fees,
             base,
             quote,
+            quote_percent_use,
+            quote_budget,
             min_base_price,
             max_base_price,
             step_quote_volume,
@@ -62,7 +76,15 @@ func (s *Mysql) Apps() ([]App, error) {
             compound_type,
             compound_details,
             publish_orders_number,
-            status
+            direction,
+            skim_percent,
+            stop_policy,
+            status,
+            is_done,
+            runtime_status,
+            last_error,
+            last_error_at,
+            start_attempts
         FROM apps
    `)
 
@@ -76,15 +98,19 @@ func (s *Mysql) Apps() ([]App, error) {
 	for rows.Next() {
 		var app App
 		var runInterval string
+		var lastErrorAt mysql.NullTime
 
 		err := rows.Scan(
 			&app.ID,
 			&runInterval,
 			&app.Exchange,
+			&app.AccountID,
 			&app.MarketOrderFees,
 			&app.LimitOrderFees,
 			&app.Base,
 			&app.Quote,
+			&app.QuotePercentUse,
+			&app.QuoteBudget,
 			&app.MinBasePrice,
 			&app.MaxBasePrice,
 			&app.StepQuoteVolume,
@@ -93,7 +119,15 @@ func (s *Mysql) Apps() ([]App, error) {
 			&app.CompoundType,
 			&app.CompoundDetails,
 			&app.PublishOrderNumber,
+			&app.Direction,
+			&app.SkimPercent,
+			&app.StopPolicy,
 			&app.Status,
+			&app.IsDone,
+			&app.RuntimeStatus,
+			&app.LastError,
+			&lastErrorAt,
+			&app.StartAttempts,
 		)
 		if err != nil {
 			return []App{}, err
@@ -105,12 +139,428 @@ func (s *Mysql) Apps() ([]App, error) {
 		}
 		app.Interval = interval
 
+		if lastErrorAt.Valid {
+			app.LastErrorAt = lastErrorAt.Time
+		}
+
 		apps = append(apps, app)
 	}
 
 	return apps, nil
 }
 
+func (s *Mysql) Accounts() ([]Account, error) {
+	rows, err := s.db.Query(`
+        SELECT
+            id,
+            exchange,
+            label,
+            credentials_ref,
+            api_key_enc,
+            secret_key_enc
+        FROM accounts
+   `)
+
+	if err != nil {
+		return []Account{}, err
+	}
+	defer rows.Close()
+
+	var accounts []Account
+
+	for rows.Next() {
+		var account Account
+
+		err := rows.Scan(
+			&account.ID,
+			&account.Exchange,
+			&account.Label,
+			&account.CredentialsRef,
+			&account.APIKeyEnc,
+			&account.SecretKeyEnc,
+		)
+		if err != nil {
+			return []Account{}, err
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+func (s *Mysql) AddAccount(account Account) (int, error) {
+	q := `
+		INSERT INTO accounts (
+			exchange,
+			label,
+			credentials_ref,
+			api_key_enc,
+			secret_key_enc
+		) VALUES (?, ?, ?, ?, ?)
+	`
+
+	res, err := s.db.Exec(q,
+		account.Exchange,
+		account.Label,
+		account.CredentialsRef,
+		account.APIKeyEnc,
+		account.SecretKeyEnc,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateAccountCredentials replaces encrypted credentials, used to rotate keys
+func (s *Mysql) UpdateAccountCredentials(accountID int, apiKeyEnc, secretKeyEnc string) error {
+	q := "UPDATE accounts SET api_key_enc = ?, secret_key_enc = ? WHERE id = ?"
+
+	res, err := s.db.Exec(q, apiKeyEnc, secretKeyEnc, accountID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("account not found: %d", accountID)
+	}
+
+	return nil
+}
+
+// AddApp inserts app config, app_id is chosen by caller and must be unique
+func (s *Mysql) AddApp(app App) error {
+	q := `
+		INSERT INTO apps (
+			app_id,
+			run_interval,
+			exchange,
+			account_id,
+			market_order_fees,
+			limit_order_fees,
+			base,
+			quote,
+			quote_percent_use,
+			quote_budget,
+			min_base_price,
+			max_base_price,
+			step_quote_volume,
+			steps_type,
+			steps_details,
+			compound_type,
+			compound_details,
+			publish_orders_number,
+			direction,
+			skim_percent,
+			stop_policy,
+			status
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(q,
+		app.ID,
+		app.Interval.String(),
+		app.Exchange,
+		app.AccountID,
+		app.MarketOrderFees,
+		app.LimitOrderFees,
+		app.Base,
+		app.Quote,
+		app.QuotePercentUse,
+		app.QuoteBudget,
+		app.MinBasePrice,
+		app.MaxBasePrice,
+		app.StepQuoteVolume,
+		app.StepsType,
+		app.StepsDetails,
+		app.CompoundType,
+		app.CompoundDetails,
+		app.PublishOrderNumber,
+		app.Direction,
+		app.SkimPercent,
+		app.StopPolicy,
+		app.Status,
+	)
+	return err
+}
+
+// UpdateApp stores app config, status and runtime columns are not changed
+func (s *Mysql) UpdateApp(app App) error {
+	q := `
+		UPDATE apps SET
+			run_interval = ?,
+			exchange = ?,
+			account_id = ?,
+			market_order_fees = ?,
+			limit_order_fees = ?,
+			base = ?,
+			quote = ?,
+			quote_percent_use = ?,
+			quote_budget = ?,
+			min_base_price = ?,
+			max_base_price = ?,
+			step_quote_volume = ?,
+			steps_type = ?,
+			steps_details = ?,
+			compound_type = ?,
+			compound_details = ?,
+			publish_orders_number = ?,
+			direction = ?,
+			skim_percent = ?,
+			stop_policy = ?
+		WHERE app_id = ?
+	`
+
+	_, err := s.db.Exec(q,
+		app.Interval.String(),
+		app.Exchange,
+		app.AccountID,
+		app.MarketOrderFees,
+		app.LimitOrderFees,
+		app.Base,
+		app.Quote,
+		app.QuotePercentUse,
+		app.QuoteBudget,
+		app.MinBasePrice,
+		app.MaxBasePrice,
+		app.StepQuoteVolume,
+		app.StepsType,
+		app.StepsDetails,
+		app.CompoundType,
+		app.CompoundDetails,
+		app.PublishOrderNumber,
+		app.Direction,
+		app.SkimPercent,
+		app.StopPolicy,
+		app.ID,
+	)
+	return err
+}
+
+// UpdateAppStatus changes app status, a new status clears is_done
+func (s *Mysql) UpdateAppStatus(appID int, status string) error {
+	_, err := s.db.Exec("UPDATE apps SET status = ?, is_done = 0 WHERE app_id = ?", status, appID)
+	return err
+}
+
+func (s *Mysql) AddAuditLog(entry AuditLog) error {
+	q := `
+		INSERT INTO audit_log (
+			actor,
+			action,
+			app_id,
+			details,
+			created_at
+		) VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(q,
+		entry.Actor,
+		entry.Action,
+		entry.AppID,
+		entry.Details,
+		sqlNullableTime(entry.CreatedAt),
+	)
+	return err
+}
+
+func (s *Mysql) AddNotifyDeadLetter(dl NotifyDeadLetter) error {
+	q := `
+		INSERT INTO notify_dead_letters (
+			channel,
+			text,
+			payload,
+			attempts,
+			error,
+			created_at
+		) VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(q,
+		dl.Channel,
+		dl.Text,
+		dl.Payload,
+		dl.Attempts,
+		dl.Error,
+		sqlNullableTime(dl.CreatedAt),
+	)
+	return err
+}
+
+func (s *Mysql) AddAPIToken(token APIToken) (int, error) {
+	q := `
+		INSERT INTO api_tokens (
+			name,
+			role,
+			token_hash,
+			created_at
+		) VALUES (?, ?, ?, ?)
+	`
+
+	res, err := s.db.Exec(q,
+		token.Name,
+		token.Role,
+		token.Hash,
+		sqlNullableTime(token.CreatedAt),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (s *Mysql) APITokens() ([]APIToken, error) {
+	rows, err := s.db.Query(`
+        SELECT
+            id,
+            name,
+            role,
+            token_hash,
+            created_at,
+            revoked_at
+        FROM api_tokens
+        ORDER BY id
+   `)
+	if err != nil {
+		return []APIToken{}, err
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return []APIToken{}, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// APITokenByHash returns token with given hash, ID is 0 when not found
+func (s *Mysql) APITokenByHash(hash string) (APIToken, error) {
+	rows, err := s.db.Query(`
+        SELECT
+            id,
+            name,
+            role,
+            token_hash,
+            created_at,
+            revoked_at
+        FROM api_tokens
+        WHERE token_hash = ?
+   `, hash)
+	if err != nil {
+		return APIToken{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return APIToken{}, nil
+	}
+
+	return scanAPIToken(rows)
+}
+
+func (s *Mysql) RevokeAPIToken(tokenID int) error {
+	res, err := s.db.Exec("UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), tokenID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("active token not found: %d", tokenID)
+	}
+
+	return nil
+}
+
+func scanAPIToken(rows *sql.Rows) (APIToken, error) {
+	var token APIToken
+	var createdAt, revokedAt mysql.NullTime
+
+	err := rows.Scan(
+		&token.ID,
+		&token.Name,
+		&token.Role,
+		&token.Hash,
+		&createdAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	if createdAt.Valid {
+		token.CreatedAt = createdAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = revokedAt.Time
+	}
+
+	return token, nil
+}
+
+// MarkAppDone is called when a winding down app has no active trades left
+func (s *Mysql) MarkAppDone(appID int) error {
+	_, err := s.db.Exec("UPDATE apps SET is_done = 1 WHERE app_id = ?", appID)
+	return err
+}
+
+// ClearAppDone resets wind down completion of an app
+func (s *Mysql) ClearAppDone(appID int) error {
+	_, err := s.db.Exec("UPDATE apps SET is_done = 0 WHERE app_id = ?", appID)
+	return err
+}
+
+// UpdateAppRuntime stores app state observed by bwd
+func (s *Mysql) UpdateAppRuntime(appID int, runtime AppRuntime) error {
+	// fit last_error column size
+	lastError := text.Truncate(runtime.LastError, 1024)
+
+	q := `
+		UPDATE apps SET
+			runtime_status = ?,
+			last_error = ?,
+			last_error_at = ?,
+			start_attempts = ?
+		WHERE app_id = ?
+	`
+
+	_, err := s.db.Exec(q,
+		runtime.Status,
+		lastError,
+		sqlNullableTime(runtime.LastErrorAt),
+		runtime.StartAttempts,
+		appID,
+	)
+	return err
+}
+
 func (s *Mysql) ActiveTrades(appID int) ([]Trade, error) {
 	startTimeMs := time.Now().UnixNano() / int64(time.Millisecond)
 
@@ -123,6 +573,7 @@ func (s *Mysql) ActiveTrades(appID int) ([]Trade, error) {
 			open_type,
 			close_type,
 			base_volume,
+			close_base_volume,
 			buy_order_id,
 			sell_order_id,
 			status,
@@ -133,7 +584,7 @@ func (s *Mysql) ActiveTrades(appID int) ([]Trade, error) {
 		FROM trades
 		WHERE 1
 			AND app_id = %d
-			AND status != 'CLOSED'
+			AND status NOT IN ('CLOSED', 'CANCELED')
        `,
 		appID,
 	)
@@ -157,6 +608,7 @@ func (s *Mysql) ActiveTrades(appID int) ([]Trade, error) {
 			&trade.OpenType,
 			&trade.CloseType,
 			&trade.BaseVolume,
+			&trade.CloseBaseVolume,
 			&trade.BuyOrderID,
 			&trade.SellOrderID,
 			&trade.Status,
@@ -192,6 +644,98 @@ func (s *Mysql) ActiveTrades(appID int) ([]Trade, error) {
 	return trades, nil
 }
 
+// Trades returns latest app trades, all statuses when statuses is empty
+func (s *Mysql) Trades(appID int, statuses []string, limit int) ([]Trade, error) {
+	args := []interface{}{appID}
+	statusFilter := ""
+	if len(statuses) > 0 {
+		placeholders := make([]string, len(statuses))
+		for i, status := range statuses {
+			placeholders[i] = "?"
+			args = append(args, status)
+		}
+		statusFilter = fmt.Sprintf("AND status IN (%s)", strings.Join(placeholders, ", "))
+	}
+	args = append(args, limit)
+
+	q := fmt.Sprintf(`
+		SELECT
+			id,
+			app_id,
+			open_base_price,
+			close_base_price,
+			open_type,
+			close_type,
+			base_volume,
+			close_base_volume,
+			buy_order_id,
+			sell_order_id,
+			status,
+			converted_sell_limit_at,
+			closed_at,
+			updated_at,
+			created_at
+		FROM trades
+		WHERE 1
+			AND app_id = ?
+			%s
+		ORDER BY id DESC
+		LIMIT ?
+	`,
+		statusFilter,
+	)
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return []Trade{}, err
+	}
+	defer rows.Close()
+
+	var trades []Trade
+	for rows.Next() {
+		var trade Trade
+		var convertedSellLimitAt, closedAt, updatedAt, createdAt mysql.NullTime
+
+		err := rows.Scan(
+			&trade.ID,
+			&trade.AppID,
+			&trade.OpenBasePrice,
+			&trade.CloseBasePrice,
+			&trade.OpenType,
+			&trade.CloseType,
+			&trade.BaseVolume,
+			&trade.CloseBaseVolume,
+			&trade.BuyOrderID,
+			&trade.SellOrderID,
+			&trade.Status,
+			&convertedSellLimitAt,
+			&closedAt,
+			&updatedAt,
+			&createdAt,
+		)
+		if err != nil {
+			return []Trade{}, err
+		}
+
+		if convertedSellLimitAt.Valid {
+			trade.ConvertedSellLimitAt = convertedSellLimitAt.Time
+		}
+		if closedAt.Valid {
+			trade.ClosedAt = closedAt.Time
+		}
+		if updatedAt.Valid {
+			trade.UpdatedAt = updatedAt.Time
+		}
+		if createdAt.Valid {
+			trade.CreatedAt = createdAt.Time
+		}
+
+		trades = append(trades, trade)
+	}
+
+	return trades, nil
+}
+
 func (s *Mysql) LatestAppClosedTradeByOpenPrice(appID int, openPrice float64) (Trade, error) {
 	q := `
 		SELECT
@@ -202,6 +746,7 @@ func (s *Mysql) LatestAppClosedTradeByOpenPrice(appID int, openPrice float64) (T
 			open_type,
 			close_type,
 			base_volume,
+			close_base_volume,
 			buy_order_id,
 			sell_order_id,
 			status,
@@ -240,6 +785,7 @@ func (s *Mysql) LatestAppClosedTradeByOpenPrice(appID int, openPrice float64) (T
 		&trade.OpenType,
 		&trade.CloseType,
 		&trade.BaseVolume,
+		&trade.CloseBaseVolume,
 		&trade.BuyOrderID,
 		&trade.SellOrderID,
 		&trade.Status,
@@ -277,13 +823,14 @@ func (s *Mysql) AddTrade(trade Trade) (int, error) {
 		    open_type,
 		    close_type,
 		    base_volume,
+		    close_base_volume,
 		    buy_order_id,
 		    sell_order_id,
 		    status,
 	        converted_sell_limit_at,
 		    closed_at,
 		    created_at
-	    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
 	resp, err := s.db.Exec(q,
@@ -293,6 +840,7 @@ func (s *Mysql) AddTrade(trade Trade) (int, error) {
 		trade.OpenType,
 		trade.CloseType,
 		trade.BaseVolume,
+		trade.CloseBaseVolume,
 		trade.BuyOrderID,
 		trade.SellOrderID,
 		trade.Status,
@@ -314,6 +862,7 @@ func (s *Mysql) UpdateTrade(trade Trade) error {
 
 	q := `
 		UPDATE trades SET
+			base_volume = ?,
 			open_type = ?,
 		    close_type = ?,
 		    buy_order_id = ?,
@@ -326,6 +875,7 @@ func (s *Mysql) UpdateTrade(trade Trade) error {
 	`
 
 	_, err := s.db.Exec(q,
+		trade.BaseVolume,
 		trade.OpenType,
 		trade.CloseType,
 		trade.BuyOrderID,
@@ -355,6 +905,11 @@ func (s *Mysql) LatestBalanceHistory(appID int) (BalanceHistory, error) {
             quote_volume,
             total_quote_net_income,
             total_quote_reinvested,
+            total_quote_reserved,
+            total_quote_withdrawn,
+            base_volume,
+            total_base_net_income,
+            total_base_reinvested,
             trade_id,
             created_at
         FROM balance_history
@@ -381,6 +936,11 @@ func (s *Mysql) LatestBalanceHistory(appID int) (BalanceHistory, error) {
 		&ab.QuoteVolume,
 		&ab.TotalNetIncome,
 		&ab.TotalReinvested,
+		&ab.TotalReserved,
+		&ab.TotalWithdrawn,
+		&ab.BaseVolume,
+		&ab.TotalBaseNetIncome,
+		&ab.TotalBaseReinvested,
 		&ab.InternalTradeID,
 		&createdAt,
 	)
@@ -395,6 +955,205 @@ func (s *Mysql) LatestBalanceHistory(appID int) (BalanceHistory, error) {
 	return ab, nil
 }
 
+// BalanceHistory returns latest app balance rows, newest first
+func (s *Mysql) BalanceHistory(appID int, limit int) ([]BalanceHistory, error) {
+	q := `
+        SELECT
+            app_id,
+            action,
+            quote_volume,
+            total_quote_net_income,
+            total_quote_reinvested,
+            total_quote_reserved,
+            total_quote_withdrawn,
+            base_volume,
+            total_base_net_income,
+            total_base_reinvested,
+            trade_id,
+            created_at
+        FROM balance_history
+        WHERE app_id = ?
+        ORDER BY id DESC
+        LIMIT ?
+    `
+
+	rows, err := s.db.Query(q, appID, limit)
+	if err != nil {
+		return []BalanceHistory{}, err
+	}
+	defer rows.Close()
+
+	var history []BalanceHistory
+	for rows.Next() {
+		var ab BalanceHistory
+		var tradeID sql.NullInt64
+		var createdAt mysql.NullTime
+
+		err := rows.Scan(
+			&ab.AppID,
+			&ab.Action,
+			&ab.QuoteVolume,
+			&ab.TotalNetIncome,
+			&ab.TotalReinvested,
+			&ab.TotalReserved,
+			&ab.TotalWithdrawn,
+			&ab.BaseVolume,
+			&ab.TotalBaseNetIncome,
+			&ab.TotalBaseReinvested,
+			&tradeID,
+			&createdAt,
+		)
+		if err != nil {
+			return []BalanceHistory{}, err
+		}
+
+		ab.InternalTradeID = int(tradeID.Int64)
+		if createdAt.Valid {
+			ab.CreatedAt = createdAt.Time
+		}
+
+		history = append(history, ab)
+	}
+
+	return history, nil
+}
+
+// BalanceHistoryBefore returns latest app balance row created before given time,
+// zero value when there is none
+func (s *Mysql) BalanceHistoryBefore(appID int, before time.Time) (BalanceHistory, error) {
+	var ab BalanceHistory
+
+	q := `
+        SELECT
+            app_id,
+            action,
+            quote_volume,
+            total_quote_net_income,
+            total_quote_reinvested,
+            total_quote_reserved,
+            total_quote_withdrawn,
+            base_volume,
+            total_base_net_income,
+            total_base_reinvested,
+            trade_id,
+            created_at
+        FROM balance_history
+        WHERE 1
+            AND app_id = ?
+            AND created_at < ?
+        ORDER BY id DESC
+        LIMIT 1
+    `
+
+	var tradeID sql.NullInt64
+	var createdAt mysql.NullTime
+	err := s.db.QueryRow(q, appID, before).Scan(
+		&ab.AppID,
+		&ab.Action,
+		&ab.QuoteVolume,
+		&ab.TotalNetIncome,
+		&ab.TotalReinvested,
+		&ab.TotalReserved,
+		&ab.TotalWithdrawn,
+		&ab.BaseVolume,
+		&ab.TotalBaseNetIncome,
+		&ab.TotalBaseReinvested,
+		&tradeID,
+		&createdAt,
+	)
+	if err == sql.ErrNoRows {
+		return BalanceHistory{}, nil
+	}
+	if err != nil {
+		return BalanceHistory{}, err
+	}
+
+	ab.InternalTradeID = int(tradeID.Int64)
+	if createdAt.Valid {
+		ab.CreatedAt = createdAt.Time
+	}
+
+	return ab, nil
+}
+
+// ClosedTradesCount counts app trades closed in [from, to)
+func (s *Mysql) ClosedTradesCount(appID int, from, to time.Time) (int, error) {
+	q := `
+        SELECT COUNT(*)
+        FROM trades
+        WHERE 1
+            AND app_id = ?
+            AND status = 'CLOSED'
+            AND closed_at >= ?
+            AND closed_at < ?
+    `
+
+	var count int
+	err := s.db.QueryRow(q, appID, from, to).Scan(&count)
+	return count, err
+}
+
+// AddDailyReport stores a report, a report of the same app and period is replaced
+func (s *Mysql) AddDailyReport(report DailyReport) error {
+	q := `
+		INSERT INTO daily_reports (
+			app_id,
+			period_start,
+			period_end,
+			trades_closed,
+			net_profit,
+			reinvested,
+			base_net_profit,
+			base_reinvested,
+			open_trades,
+			capital_locked,
+			capital_locked_asset,
+			created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			period_start = VALUES(period_start),
+			trades_closed = VALUES(trades_closed),
+			net_profit = VALUES(net_profit),
+			reinvested = VALUES(reinvested),
+			base_net_profit = VALUES(base_net_profit),
+			base_reinvested = VALUES(base_reinvested),
+			open_trades = VALUES(open_trades),
+			capital_locked = VALUES(capital_locked),
+			capital_locked_asset = VALUES(capital_locked_asset),
+			created_at = VALUES(created_at)
+	`
+
+	_, err := s.db.Exec(q,
+		report.AppID,
+		sqlNullableTime(report.PeriodStart),
+		sqlNullableTime(report.PeriodEnd),
+		report.TradesClosed,
+		report.NetProfit,
+		report.Reinvested,
+		report.BaseNetProfit,
+		report.BaseReinvested,
+		report.OpenTrades,
+		report.CapitalLocked,
+		report.CapitalLockedAsset,
+		sqlNullableTime(report.CreatedAt),
+	)
+	return err
+}
+
+// LatestDailyReportEnd returns end of latest reported period, zero when none
+func (s *Mysql) LatestDailyReportEnd() (time.Time, error) {
+	var end mysql.NullTime
+	if err := s.db.QueryRow("SELECT MAX(period_end) FROM daily_reports").Scan(&end); err != nil {
+		return time.Time{}, err
+	}
+
+	if !end.Valid {
+		return time.Time{}, nil
+	}
+
+	return end.Time, nil
+}
+
 // LatestTradeBalanceHistory ...
 func (s *Mysql) LatestTradeBalanceHistory(appID int, tradeID int) (BalanceHistory, error) {
 	var ab BalanceHistory
@@ -406,6 +1165,11 @@ func (s *Mysql) LatestTradeBalanceHistory(appID int, tradeID int) (BalanceHistor
             quote_volume,
             total_quote_net_income,
             total_quote_reinvested,
+            total_quote_reserved,
+            total_quote_withdrawn,
+            base_volume,
+            total_base_net_income,
+            total_base_reinvested,
             trade_id,
             created_at
         FROM balance_history
@@ -435,6 +1199,11 @@ func (s *Mysql) LatestTradeBalanceHistory(appID int, tradeID int) (BalanceHistor
 		&ab.QuoteVolume,
 		&ab.TotalNetIncome,
 		&ab.TotalReinvested,
+		&ab.TotalReserved,
+		&ab.TotalWithdrawn,
+		&ab.BaseVolume,
+		&ab.TotalBaseNetIncome,
+		&ab.TotalBaseReinvested,
 		&ab.InternalTradeID,
 		&createdAt,
 	)
@@ -450,29 +1219,116 @@ func (s *Mysql) LatestTradeBalanceHistory(appID int, tradeID int) (BalanceHistor
 }
 
 // AddAppBalanceEntry ...
-func (s *Mysql) AddBalanceHistory(appID int, balance BalanceHistory) error {
+// AppendBalanceHistory inserts the row built by next from app latest balance,
+// running totals are copied from latest row so app row is locked to serialise
+// writers (trader and bwdctl run in different processes), next returns false
+// when there is nothing to append
+func (s *Mysql) AppendBalanceHistory(appID int, next func(latest BalanceHistory) (BalanceHistory, bool, error)) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec("SELECT app_id FROM apps WHERE app_id = ? FOR UPDATE", appID); err != nil {
+		return err
+	}
+
 	q := `
+        SELECT
+            app_id,
+            action,
+            quote_volume,
+            total_quote_net_income,
+            total_quote_reinvested,
+            total_quote_reserved,
+            total_quote_withdrawn,
+            base_volume,
+            total_base_net_income,
+            total_base_reinvested,
+            trade_id,
+            created_at
+        FROM balance_history
+        WHERE app_id = ?
+        ORDER BY id DESC
+        LIMIT 1
+        FOR UPDATE
+    `
+
+	var latest BalanceHistory
+	var tradeID sql.NullInt64
+	var createdAt mysql.NullTime
+	err = tx.QueryRow(q, appID).Scan(
+		&latest.AppID,
+		&latest.Action,
+		&latest.QuoteVolume,
+		&latest.TotalNetIncome,
+		&latest.TotalReinvested,
+		&latest.TotalReserved,
+		&latest.TotalWithdrawn,
+		&latest.BaseVolume,
+		&latest.TotalBaseNetIncome,
+		&latest.TotalBaseReinvested,
+		&tradeID,
+		&createdAt,
+	)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	latest.InternalTradeID = int(tradeID.Int64)
+	if createdAt.Valid {
+		latest.CreatedAt = createdAt.Time
+	}
+
+	balance, ok, err := next(latest)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		err = tx.Rollback()
+		return err
+	}
+
+	q = `
 		INSERT INTO balance_history (
 			app_id,
 		    action,
 		    quote_volume,
 		    total_quote_net_income,
 		    total_quote_reinvested,
+		    total_quote_reserved,
+		    total_quote_withdrawn,
+		    base_volume,
+		    total_base_net_income,
+		    total_base_reinvested,
 		    trade_id,
 		    created_at
-        ) VALUES (?, ?, ?, ?, ?, ?, ?)
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
-	_, err := s.db.Exec(q,
+	_, err = tx.Exec(q,
 		appID,
 		balance.Action,
 		balance.QuoteVolume,
 		balance.TotalNetIncome,
 		balance.TotalReinvested,
+		balance.TotalReserved,
+		balance.TotalWithdrawn,
+		balance.BaseVolume,
+		balance.TotalBaseNetIncome,
+		balance.TotalBaseReinvested,
 		balance.InternalTradeID,
 		sqlNullableTime(balance.CreatedAt),
 	)
+	if err != nil {
+		return err
+	}
 
+	err = tx.Commit()
 	return err
 }
 
@@ -503,7 +1359,7 @@ func (s *Mysql) createSchemaIfNotExists() error {
             steps_type VARCHAR(32) DEFAULT '',
             steps_details VARCHAR(32) DEFAULT '',
             compound_type VARCHAR(32) DEFAULT '',
-            compound_details VARCHAR(32) DEFAULT '',
+            compound_details VARCHAR(1024) DEFAULT '',
             publish_orders_number INT DEFAULT 0,
             status VARCHAR(32) DEFAULT ''
         )    
@@ -548,6 +1404,119 @@ func (s *Mysql) createSchemaIfNotExists() error {
 		return err
 	}
 
+	q = `
+        CREATE TABLE IF NOT EXISTS accounts (
+            id INT PRIMARY KEY AUTO_INCREMENT,
+            exchange VARCHAR(32) DEFAULT '',
+            label VARCHAR(64) NOT NULL UNIQUE,
+            credentials_ref VARCHAR(256) DEFAULT ''
+        )
+    `
+
+	stmt, err = s.db.Prepare(q)
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	q = `
+        CREATE TABLE IF NOT EXISTS audit_log (
+            id INT PRIMARY KEY AUTO_INCREMENT,
+            actor VARCHAR(64) DEFAULT '',
+            action VARCHAR(64) DEFAULT '',
+            app_id INT DEFAULT 0,
+            details TEXT,
+            created_at TIMESTAMP NULL,
+            INDEX APP_ID (app_id)
+        )
+    `
+
+	stmt, err = s.db.Prepare(q)
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	q = `
+        CREATE TABLE IF NOT EXISTS daily_reports (
+            id INT PRIMARY KEY AUTO_INCREMENT,
+            app_id INT,
+            period_start TIMESTAMP NULL,
+            period_end TIMESTAMP NULL,
+            trades_closed INT DEFAULT 0,
+            net_profit DECIMAL(16,10) DEFAULT 0,
+            reinvested DECIMAL(16,10) DEFAULT 0,
+            base_net_profit DECIMAL(16,10) DEFAULT 0,
+            base_reinvested DECIMAL(16,10) DEFAULT 0,
+            open_trades INT DEFAULT 0,
+            capital_locked DECIMAL(20,10) DEFAULT 0,
+            capital_locked_asset VARCHAR(32) DEFAULT '',
+            created_at TIMESTAMP NULL,
+            UNIQUE INDEX APP_PERIOD (app_id, period_end)
+        )
+    `
+
+	stmt, err = s.db.Prepare(q)
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	q = `
+        CREATE TABLE IF NOT EXISTS notify_dead_letters (
+            id INT PRIMARY KEY AUTO_INCREMENT,
+            channel VARCHAR(64) DEFAULT '',
+            text TEXT,
+            payload TEXT,
+            attempts INT DEFAULT 0,
+            error VARCHAR(1024) DEFAULT '',
+            created_at TIMESTAMP NULL
+        )
+    `
+
+	stmt, err = s.db.Prepare(q)
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	q = `
+        CREATE TABLE IF NOT EXISTS api_tokens (
+            id INT PRIMARY KEY AUTO_INCREMENT,
+            name VARCHAR(64) DEFAULT '',
+            role VARCHAR(32) DEFAULT '',
+            token_hash CHAR(64) UNIQUE,
+            created_at TIMESTAMP NULL,
+            revoked_at TIMESTAMP NULL
+        )
+    `
+
+	stmt, err = s.db.Prepare(q)
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
 	q = `
         CREATE TABLE IF NOT EXISTS balance_history (
             id INT PRIMARY KEY AUTO_INCREMENT,
@@ -574,3 +1543,77 @@ func (s *Mysql) createSchemaIfNotExists() error {
 
 	return nil
 }
+
+// migrateSchema adds columns introduced after initial schema on existing tables
+func (s *Mysql) migrateSchema() error {
+	columns := []struct {
+		table, column, definition string
+	}{
+		{"apps", "direction", "VARCHAR(32) DEFAULT 'BUY_SELL'"},
+		{"trades", "close_base_volume", "DECIMAL(16,10) DEFAULT 0"},
+		{"balance_history", "base_volume", "DECIMAL(16,10) DEFAULT 0"},
+		{"balance_history", "total_base_net_income", "DECIMAL(16,10) DEFAULT 0"},
+		{"balance_history", "total_base_reinvested", "DECIMAL(16,10) DEFAULT 0"},
+		{"apps", "skim_percent", "DECIMAL(5,2) DEFAULT 0"},
+		{"balance_history", "total_quote_reserved", "DECIMAL(16,10) DEFAULT 0"},
+		{"balance_history", "total_quote_withdrawn", "DECIMAL(16,10) DEFAULT 0"},
+		{"apps", "quote_percent_use", "DECIMAL(5,2) DEFAULT 0"},
+		{"apps", "quote_budget", "DECIMAL(16,10) DEFAULT 0"},
+		{"apps", "is_done", "TINYINT(1) DEFAULT 0"},
+		{"apps", "stop_policy", "VARCHAR(32) DEFAULT 'LEAVE'"},
+		{"apps", "runtime_status", "VARCHAR(32) DEFAULT ''"},
+		{"apps", "last_error", "VARCHAR(1024) DEFAULT ''"},
+		{"apps", "last_error_at", "TIMESTAMP NULL"},
+		{"apps", "start_attempts", "INT DEFAULT 0"},
+		{"apps", "account_id", "INT DEFAULT 0"},
+		{"accounts", "api_key_enc", "VARCHAR(1024) DEFAULT ''"},
+		{"accounts", "secret_key_enc", "VARCHAR(1024) DEFAULT ''"},
+		{"daily_reports", "capital_locked_asset", "VARCHAR(32) DEFAULT ''"},
+	}
+
+	for _, c := range columns {
+		if err := s.addColumnIfNotExists(c.table, c.column, c.definition); err != nil {
+			return fmt.Errorf("fail add column %s.%s, err: %w", c.table, c.column, err)
+		}
+	}
+
+	// column changes, statements should be idempotent
+	alters := []string{
+		// accounts created before label was required
+		"UPDATE accounts SET label = CONCAT('account-', id) WHERE label IS NULL",
+		"ALTER TABLE accounts MODIFY COLUMN label VARCHAR(64) NOT NULL",
+		"ALTER TABLE apps MODIFY COLUMN compound_details VARCHAR(1024) DEFAULT ''",
+		"ALTER TABLE apps MODIFY COLUMN steps_details VARCHAR(1024) DEFAULT ''",
+	}
+
+	for _, q := range alters {
+		if _, err := s.db.Exec(q); err != nil {
+			return fmt.Errorf("fail alter schema: %s, err: %w", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Mysql) addColumnIfNotExists(table, column, definition string) error {
+	q := `
+        SELECT COUNT(*)
+        FROM information_schema.COLUMNS
+        WHERE 1
+            AND TABLE_SCHEMA = DATABASE()
+            AND TABLE_NAME = ?
+            AND COLUMN_NAME = ?
+    `
+
+	var count int
+	if err := s.db.QueryRow(q, table, column).Scan(&count); err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	_, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -5,7 +5,14 @@ import "time"
 // Bwd interface
 type Storer interface {
 	// Bwd
+	Ping() error
 	Apps() ([]App, error)
+	Accounts() ([]Account, error)
+	AddAccount(account Account) (int, error)
+	UpdateAccountCredentials(accountID int, apiKeyEnc, secretKeyEnc string) error
+	MarkAppDone(appID int) error
+	ClearAppDone(appID int) error
+	UpdateAppRuntime(appID int, runtime AppRuntime) error
 	// Trader
 	ActiveTrades(appID int) ([]Trade, error)
 	AddTrade(trade Trade) (int, error)
@@ -13,19 +20,38 @@ type Storer interface {
 	// Compounder
 	LatestBalanceHistory(appID int) (BalanceHistory, error)
 	LatestTradeBalanceHistory(appID int, tradeID int) (BalanceHistory, error)
-	AddBalanceHistory(appID int, balance BalanceHistory) error
+	AppendBalanceHistory(appID int, next func(latest BalanceHistory) (BalanceHistory, bool, error)) error
 	LatestAppClosedTradeByOpenPrice(appID int, openPrice float64) (Trade, error)
+	// Api
+	Trades(appID int, statuses []string, limit int) ([]Trade, error)
+	BalanceHistory(appID int, limit int) ([]BalanceHistory, error)
+	AddApp(app App) error
+	UpdateApp(app App) error
+	UpdateAppStatus(appID int, status string) error
+	AddAuditLog(entry AuditLog) error
+	AddNotifyDeadLetter(dl NotifyDeadLetter) error
+	AddAPIToken(token APIToken) (int, error)
+	APITokens() ([]APIToken, error)
+	APITokenByHash(hash string) (APIToken, error)
+	RevokeAPIToken(tokenID int) error
+	// Reports
+	BalanceHistoryBefore(appID int, before time.Time) (BalanceHistory, error)
+	ClosedTradesCount(appID int, from, to time.Time) (int, error)
+	AddDailyReport(report DailyReport) error
+	LatestDailyReportEnd() (time.Time, error)
 }
 
 type App struct {
 	ID                 int
 	Interval           time.Duration
 	Exchange           string
+	AccountID          int
 	MarketOrderFees    float64
 	LimitOrderFees     float64
 	Base               string
 	Quote              string
 	QuotePercentUse    float64
+	QuoteBudget        float64
 	MinBasePrice       float64
 	MaxBasePrice       float64
 	StepQuoteVolume    float64
@@ -34,8 +60,82 @@ type App struct {
 	CompoundType       string
 	CompoundDetails    string
 	PublishOrderNumber int
+	Direction          string
+	SkimPercent        float64
+	StopPolicy         string
 	Status             string
 	IsDone             bool
+	RuntimeStatus      string
+	LastError          string
+	LastErrorAt        time.Time
+	StartAttempts      int
+}
+
+// Account is an exchange account, apps of the same account share one connector
+// credentials are stored encrypted with master key, when missing CredentialsRef
+// names where account credentials are read from
+type Account struct {
+	ID             int
+	Exchange       string
+	Label          string
+	CredentialsRef string
+	APIKeyEnc      string `json:"-"`
+	SecretKeyEnc   string `json:"-"`
+}
+
+// AppRuntime is app state observed by bwd
+type AppRuntime struct {
+	Status        string
+	LastError     string
+	LastErrorAt   time.Time
+	StartAttempts int
+}
+
+// AuditLog records a change made through admin api
+type AuditLog struct {
+	Actor     string
+	Action    string
+	AppID     int
+	Details   string
+	CreatedAt time.Time
+}
+
+// NotifyDeadLetter is a notification message that could not be delivered
+type NotifyDeadLetter struct {
+	Channel   string
+	Text      string
+	Payload   string
+	Attempts  int
+	Error     string
+	CreatedAt time.Time
+}
+
+// APIToken grants api access, only token hash is stored
+type APIToken struct {
+	ID        int
+	Name      string
+	Role      string
+	Hash      string
+	CreatedAt time.Time
+	RevokedAt time.Time
+}
+
+// DailyReport aggregates app activity over a period of a day, profit amounts
+// are realized in quote and base assets
+type DailyReport struct {
+	AppID          int
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+	TradesClosed   int
+	NetProfit      float64
+	Reinvested     float64
+	BaseNetProfit  float64
+	BaseReinvested float64
+	OpenTrades     int
+	CapitalLocked  float64
+	// CapitalLockedAsset is quote for BUY_SELL apps and base for SELL_BUY apps
+	CapitalLockedAsset string
+	CreatedAt          time.Time
 }
 
 type Trade struct {
@@ -46,6 +146,7 @@ type Trade struct {
 	OpenType             string
 	CloseType            string
 	BaseVolume           float64
+	CloseBaseVolume      float64
 	BuyOrderID           string
 	SellOrderID          string
 	Status               string
@@ -56,11 +157,16 @@ type Trade struct {
 }
 
 type BalanceHistory struct {
-	AppID           int
-	Action          string
-	QuoteVolume     float64
-	TotalNetIncome  float64
-	TotalReinvested float64
-	InternalTradeID int
-	CreatedAt       time.Time
+	AppID               int
+	Action              string
+	QuoteVolume         float64
+	TotalNetIncome      float64
+	TotalReinvested     float64
+	TotalReserved       float64
+	TotalWithdrawn      float64
+	BaseVolume          float64
+	TotalBaseNetIncome  float64
+	TotalBaseReinvested float64
+	InternalTradeID     int
+	CreatedAt           time.Time
 }
@@ -14,6 +14,7 @@ func castStorageTrade(st storage.Trade) trade {
 		openType:             st.OpenType,
 		closeType:            st.CloseType,
 		baseVolume:           st.BaseVolume,
+		closeBaseVolume:      st.CloseBaseVolume,
 		buyOrderID:           st.BuyOrderID,
 		sellOrderID:          st.SellOrderID,
 		status:               st.Status,
@@ -33,6 +34,7 @@ func castToStorageTrade(trade trade) storage.Trade {
 		OpenType:             trade.openType,
 		CloseType:            trade.closeType,
 		BaseVolume:           trade.baseVolume,
+		CloseBaseVolume:      trade.closeBaseVolume,
 		BuyOrderID:           trade.buyOrderID,
 		SellOrderID:          trade.sellOrderID,
 		Status:               trade.status,
@@ -58,24 +60,34 @@ func castOrder(o connector.Order) order {
 
 func castStorageBalanceHistory(history storage.BalanceHistory) balanceHistory {
 	return balanceHistory{
-		appID:           history.AppID,
-		action:          history.Action,
-		quoteVolume:     history.QuoteVolume,
-		totalNetIncome:  history.TotalNetIncome,
-		totalReinvested: history.TotalReinvested,
-		internalTradeID: history.InternalTradeID,
-		createdAt:       history.CreatedAt,
+		appID:               history.AppID,
+		action:              history.Action,
+		quoteVolume:         history.QuoteVolume,
+		totalNetIncome:      history.TotalNetIncome,
+		totalReinvested:     history.TotalReinvested,
+		totalReserved:       history.TotalReserved,
+		totalWithdrawn:      history.TotalWithdrawn,
+		baseVolume:          history.BaseVolume,
+		totalBaseNetIncome:  history.TotalBaseNetIncome,
+		totalBaseReinvested: history.TotalBaseReinvested,
+		internalTradeID:     history.InternalTradeID,
+		createdAt:           history.CreatedAt,
 	}
 }
 
 func castToStorageBalanceHistory(history balanceHistory) storage.BalanceHistory {
 	return storage.BalanceHistory{
-		AppID:           history.appID,
-		Action:          history.action,
-		QuoteVolume:     history.quoteVolume,
-		TotalNetIncome:  history.totalNetIncome,
-		TotalReinvested: history.totalReinvested,
-		InternalTradeID: history.internalTradeID,
-		CreatedAt:       history.createdAt,
+		AppID:               history.appID,
+		Action:              history.action,
+		QuoteVolume:         history.quoteVolume,
+		TotalNetIncome:      history.totalNetIncome,
+		TotalReinvested:     history.totalReinvested,
+		TotalReserved:       history.totalReserved,
+		TotalWithdrawn:      history.totalWithdrawn,
+		BaseVolume:          history.baseVolume,
+		TotalBaseNetIncome:  history.totalBaseNetIncome,
+		TotalBaseReinvested: history.totalBaseReinvested,
+		InternalTradeID:     history.internalTradeID,
+		CreatedAt:           history.createdAt,
 	}
 }
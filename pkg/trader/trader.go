@@ -3,11 +3,15 @@ package trader
 import (
 	"bwd/pkg/compound"
 	"bwd/pkg/connector"
+	"bwd/pkg/event"
 	"bwd/pkg/step"
 	"bwd/pkg/storage"
+	"bwd/pkg/utils/decimal"
 	"bwd/pkg/utils/metrics/exporter"
 	"fmt"
+	"math"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -25,6 +29,39 @@ const (
 	statusBuyLimitExecuted      = "BUY_LIMIT_EXECUTED"
 	statusSellLimitExecuted     = "SELL_LIMIT_EXECUTED"
 	statusClosed                = "CLOSED"
+	statusCanceled              = "CANCELED"
+
+	// sell/buy (inverse) grid statuses, trade opens with a sell and closes with a lower buy
+	statusOpenSellLimit             = "OPEN_SELL_LIMIT"
+	statusCloseBuyLimit             = "CLOSE_BUY_LIMIT"
+	statusOpenSellLimitWantsPublish = "OPEN_SELL_LIMIT_WANTS_PUBLISH"
+	statusCloseBuyLimitWantsPublish = "CLOSE_BUY_LIMIT_WANTS_PUBLISH"
+	statusOpenSellLimitPublished    = "OPEN_SELL_LIMIT_PUBLISHED"
+	statusCloseBuyLimitPublished    = "CLOSE_BUY_LIMIT_PUBLISHED"
+	statusOpenSellLimitExecuted     = "OPEN_SELL_LIMIT_EXECUTED"
+	statusCloseBuyLimitExecuted     = "CLOSE_BUY_LIMIT_EXECUTED"
+)
+
+const (
+	balanceActionCashedIn = "CASHED_IN"
+	balanceActionReinvest = "REINVEST"
+	balanceActionReserve  = "RESERVE"
+)
+
+const (
+	// StopPolicyLeave keeps all orders live on exchange when app stops
+	StopPolicyLeave = "LEAVE"
+	// StopPolicyCancelBuys cancels not filled buy orders when app stops
+	StopPolicyCancelBuys = "CANCEL_BUYS"
+	// StopPolicyCancelAll cancels all not filled orders when app stops
+	StopPolicyCancelAll = "CANCEL_ALL"
+)
+
+const (
+	// DirectionBuySell grids buy low and sell high, profit is accumulated in quote
+	DirectionBuySell = "BUY_SELL"
+	// DirectionSellBuy grids sell high and buy back low, profit is accumulated in base
+	DirectionSellBuy = "SELL_BUY"
 )
 
 var (
@@ -34,6 +71,15 @@ var (
 	metricMarkPublishLatency   = exporter.GetHistogram("bwd", "trader_mark_publish_unpublish_ms_latency", []string{"appid"})
 	metricPublishTradesLatency = exporter.GetHistogram("bwd", "trader_publish_trades_ms_latency", []string{"appid"})
 	metricRunTotalLatency      = exporter.GetHistogram("bwd", "trader_run_total_ms_latency", []string{"appid"})
+	metricUnderfundedTrades    = exporter.GetCounter("bwd", "trader_underfunded_trades_count", []string{"appid", "action"})
+	metricOverBudgetTrades     = exporter.GetCounter("bwd", "trader_over_budget_trades_count", []string{"appid", "action"})
+)
+
+const (
+	underfundedActionSkip   = "skip"
+	underfundedActionShrink = "shrink"
+	overBudgetActionCreate  = "create"
+	overBudgetActionPublish = "publish"
 )
 
 type ConfigTrader struct {
@@ -42,10 +88,19 @@ type ConfigTrader struct {
 	Quote           string
 	MarketOrderFees float64
 	LimitOrderFees  float64
+	BaseLotTick     float64
+	BaseLotMin      float64
+	QuoteMinVolume  float64
+	QuoteBudget     float64
+	QuotePercentUse float64
+	Direction       string
+	SkimPercent     float64
 	Storer          storage.Storer
 	Connector       connector.Connector
 	Stepper         step.Stepper
 	Compounder      compound.Compounder
+	// Events receives trade domain events, optional
+	Events event.Publisher
 }
 
 type Trader struct {
@@ -55,10 +110,21 @@ type Trader struct {
 	quote           string
 	marketOrderFees float64
 	limitOrderFees  float64
+	baseLotTick     float64
+	baseLotMin      float64
+	quoteMinVolume  float64
+	quoteBudget     float64
+	quotePercentUse float64
+	direction       string
+	skimPercent     float64
 	storer          storage.Storer
 	connector       connector.Connector
 	stepper         step.Stepper
 	compounder      compound.Compounder
+	events          event.Publisher
+	// accessed atomically, set from app goroutine
+	windingDown int32
+	woundDown   int32
 }
 
 func New(cfg *ConfigTrader, logger logrus.FieldLogger) *Trader {
@@ -69,10 +135,18 @@ func New(cfg *ConfigTrader, logger logrus.FieldLogger) *Trader {
 		quote:           cfg.Quote,
 		marketOrderFees: cfg.MarketOrderFees,
 		limitOrderFees:  cfg.LimitOrderFees,
+		baseLotTick:     cfg.BaseLotTick,
+		baseLotMin:      cfg.BaseLotMin,
+		quoteMinVolume:  cfg.QuoteMinVolume,
+		quoteBudget:     cfg.QuoteBudget,
+		quotePercentUse: cfg.QuotePercentUse,
+		direction:       cfg.Direction,
+		skimPercent:     cfg.SkimPercent,
 		storer:          cfg.Storer,
 		connector:       cfg.Connector,
 		stepper:         cfg.Stepper,
 		compounder:      cfg.Compounder,
+		events:          cfg.Events,
 	}
 }
 
@@ -96,11 +170,23 @@ func (t *Trader) Run() {
 		return
 	}
 
-	// create missing trades
-	if ok := t.addMissingTrades(); !ok {
+	// reserve share of profit for withdraw before it can be reinvested
+	if ok := t.reserveProfit(); !ok {
 		return
 	}
 
+	if t.IsWindingDown() {
+		// stop opening trades, close trades still waiting for opening order
+		if ok := t.cancelUnfilledOpenTrades(); !ok {
+			return
+		}
+	} else {
+		// create missing trades
+		if ok := t.addMissingTrades(); !ok {
+			return
+		}
+	}
+
 	// decide who need to publish / unPublish
 	if ok := t.markForPublishUnPublish(); !ok {
 		return
@@ -111,11 +197,269 @@ func (t *Trader) Run() {
 		return
 	}
 
+	if t.IsWindingDown() {
+		t.checkWoundDown()
+	}
+
 	labels := prometheus.Labels{"appid": strconv.Itoa(t.appID)}
 	endTimeMs := time.Now().UnixNano() / int64(time.Millisecond)
 	metricRunTotalLatency.With(labels).Observe(float64(endTimeMs - startTimeMs))
 }
 
+// SetFees changes fees used for profit calculation, should be called between runs
+func (t *Trader) SetFees(market, limit float64) {
+	t.marketOrderFees = market
+	t.limitOrderFees = limit
+}
+
+// WindDown stops trader from opening new trades, open trades are still managed until closed
+func (t *Trader) WindDown() {
+	atomic.StoreInt32(&t.windingDown, 1)
+}
+
+func (t *Trader) IsWindingDown() bool {
+	return atomic.LoadInt32(&t.windingDown) == 1
+}
+
+// IsWoundDown returns true when a winding down trader has no active trades left
+func (t *Trader) IsWoundDown() bool {
+	return atomic.LoadInt32(&t.woundDown) == 1
+}
+
+func (t *Trader) checkWoundDown() {
+	trades, err := t.activeTrades()
+	if err != nil {
+		t.logger.WithError(err).Error("checkWoundDown: fail fetch active trades")
+		return
+	}
+
+	if len(trades) == 0 {
+		atomic.StoreInt32(&t.woundDown, 1)
+	}
+}
+
+// UnpublishOrders cancels not filled exchange orders of given sides and moves their trades
+// back to unpublished statuses, so they are published again when trader runs
+func (t *Trader) UnpublishOrders(sides []string) bool {
+	trades, err := t.activeTrades()
+	if err != nil {
+		t.logger.WithError(err).Error("UnpublishOrders: fail fetch active trades")
+		return false
+	}
+
+	cancelSide := make(map[string]bool)
+	for _, side := range sides {
+		cancelSide[side] = true
+	}
+
+	isOk := true
+
+	for _, trd := range trades {
+		logger := t.logger.
+			WithField("datatrade", fmt.Sprintf("%+v", trd)).
+			WithField("datatradeid", trd.id)
+
+		var orderID, side, unpublishedStatus string
+		switch trd.status {
+		case statusBuyLimitPublished:
+			orderID, side, unpublishedStatus = trd.buyOrderID, connector.OrderSideBuy, statusBuyLimit
+		case statusSellLimitPublished:
+			orderID, side, unpublishedStatus = trd.sellOrderID, connector.OrderSideSell, statusSellLimit
+		case statusOpenSellLimitPublished:
+			orderID, side, unpublishedStatus = trd.sellOrderID, connector.OrderSideSell, statusOpenSellLimit
+		case statusCloseBuyLimitPublished:
+			orderID, side, unpublishedStatus = trd.buyOrderID, connector.OrderSideBuy, statusCloseBuyLimit
+		default:
+			continue
+		}
+
+		if !cancelSide[side] {
+			continue
+		}
+
+		connectorOrder := connector.Order{
+			ID:    orderID,
+			Base:  t.base,
+			Quote: t.quote,
+		}
+		o, err := t.connector.OrderDetails(t.appID, connectorOrder)
+		if err != nil {
+			logger.WithError(err).Error("UnpublishOrders: fail connector OrderDetails")
+			isOk = false
+			continue
+		}
+
+		// filled volume can not be moved back, partially filled orders stay live
+		if o.Status != connector.OrderStatusNew {
+			continue
+		}
+
+		if err := t.connector.CancelOrder(o); err != nil {
+			logger.WithError(err).Error("UnpublishOrders: fail cancel exchange order")
+			isOk = false
+			continue
+		}
+
+		if side == connector.OrderSideBuy {
+			trd.buyOrderID = ""
+		} else {
+			trd.sellOrderID = ""
+		}
+		trd.status = unpublishedStatus
+
+		if err := t.storer.UpdateTrade(castToStorageTrade(trd)); err != nil {
+			logger.WithError(err).Error("UnpublishOrders: fail update trade")
+			isOk = false
+			continue
+		}
+
+		logger.Info("order canceled, trade unpublished")
+	}
+
+	return isOk
+}
+
+// cancelUnfilledOpenTrades cancels trades whose opening order was not published or not filled at all
+func (t *Trader) cancelUnfilledOpenTrades() bool {
+	trades, err := t.activeTrades()
+	if err != nil {
+		t.logger.WithError(err).Error("cancelUnfilledOpenTrades: fail fetch active trades")
+		return false
+	}
+
+	isOk := true
+
+	for _, trd := range trades {
+		logger := t.logger.
+			WithField("datatrade", fmt.Sprintf("%+v", trd)).
+			WithField("datatradeid", trd.id)
+
+		var orderID string
+		switch trd.status {
+		case statusBuyLimit, statusBuyLimitWantsPublish, statusOpenSellLimit, statusOpenSellLimitWantsPublish:
+		case statusBuyLimitPublished:
+			orderID = trd.buyOrderID
+		case statusOpenSellLimitPublished:
+			orderID = trd.sellOrderID
+		default:
+			continue
+		}
+
+		if orderID != "" {
+			connectorOrder := connector.Order{
+				ID:    orderID,
+				Base:  t.base,
+				Quote: t.quote,
+			}
+			o, err := t.connector.OrderDetails(t.appID, connectorOrder)
+			if err != nil {
+				logger.WithError(err).Error("cancelUnfilledOpenTrades: fail connector OrderDetails")
+				isOk = false
+				continue
+			}
+
+			switch o.Status {
+			case connector.OrderStatusNew:
+				if err := t.connector.CancelOrder(o); err != nil {
+					logger.WithError(err).Error("cancelUnfilledOpenTrades: fail cancel exchange order")
+					isOk = false
+					continue
+				}
+			case connector.OrderStatusPartiallyFilled:
+				if ok := t.cancelPartiallyFilledOpenTrade(trd, o); !ok {
+					isOk = false
+				}
+				continue
+			case connector.OrderStatusCanceled:
+				// canceled after a partial fill, previous run failed to keep filled part
+				if ok := t.keepFilledOpenTrade(trd, o); !ok {
+					isOk = false
+				}
+				continue
+			default:
+				continue
+			}
+		}
+
+		trd.status = statusCanceled
+		trd.closedAt = time.Now().UTC()
+
+		if err := t.storer.UpdateTrade(castToStorageTrade(trd)); err != nil {
+			logger.WithError(err).Error("cancelUnfilledOpenTrades: fail update trade")
+			isOk = false
+			continue
+		}
+
+		logger.Info("trade canceled on wind down")
+	}
+
+	return isOk
+}
+
+// cancelPartiallyFilledOpenTrade cancels the rest of a partially filled open
+// order, filled part is kept and closed like an executed trade
+func (t *Trader) cancelPartiallyFilledOpenTrade(trd trade, o connector.Order) bool {
+	logger := t.logger.
+		WithField("datatrade", fmt.Sprintf("%+v", trd)).
+		WithField("datatradeid", trd.id)
+
+	if err := t.connector.CancelOrder(o); err != nil {
+		logger.WithError(err).Error("cancelPartiallyFilledOpenTrade: fail cancel exchange order")
+		return false
+	}
+
+	// order can fill more until it is canceled, fetch final filled volume
+	canceled, err := t.connector.OrderDetails(t.appID, o)
+	if err != nil {
+		logger.WithError(err).Error("cancelPartiallyFilledOpenTrade: fail connector OrderDetails")
+		return false
+	}
+
+	return t.keepFilledOpenTrade(trd, canceled)
+}
+
+// keepFilledOpenTrade moves trade of a canceled open order to executed with
+// filled volume, trade is canceled when filled part can not be closed on exchange
+func (t *Trader) keepFilledOpenTrade(trd trade, o connector.Order) bool {
+	logger := t.logger.
+		WithField("datatrade", fmt.Sprintf("%+v", trd)).
+		WithField("datatradeid", trd.id).
+		WithField("dataorder", fmt.Sprintf("%+v", o))
+
+	filled := o.FilledVolume
+	if t.baseLotTick > 0 {
+		filled = decimal.FloorToTick(filled, t.baseLotTick)
+	}
+
+	closeVolume := filled
+	if t.direction == DirectionSellBuy {
+		closeVolume = t.closeBaseVolume(trd.openBasePrice, trd.closeBasePrice, filled, connector.OrderTypeLimit)
+	}
+
+	if filled <= 0 || closeVolume < t.baseLotMin || closeVolume*trd.closeBasePrice < t.quoteMinVolume {
+		if filled > 0 {
+			logger.Warnf("keepFilledOpenTrade: filled volume %v can not be closed on exchange, left on account", filled)
+		}
+		trd.status = statusCanceled
+		trd.closedAt = time.Now().UTC()
+	} else {
+		trd.baseVolume = filled
+		trd.openType = connector.OrderTypeLimit
+		trd.status = statusBuyLimitExecuted
+		if t.direction == DirectionSellBuy {
+			trd.status = statusOpenSellLimitExecuted
+		}
+	}
+
+	if err := t.storer.UpdateTrade(castToStorageTrade(trd)); err != nil {
+		logger.WithError(err).Error("keepFilledOpenTrade: fail update trade")
+		return false
+	}
+
+	logger.WithField("status", trd.status).Info("partially filled trade handled on wind down")
+	return true
+}
+
 // only trades with statuses buyLimitPublished/sellLimitPublished will be reconciled
 func (t *Trader) reconcileStorageTrades() bool {
 	startTimeMs := time.Now().UnixNano() / int64(time.Millisecond)
@@ -141,6 +485,10 @@ func (t *Trader) reconcileStorageTrades() bool {
 			orderID = trd.buyOrderID
 		case statusSellLimitPublished:
 			orderID = trd.sellOrderID
+		case statusOpenSellLimitPublished:
+			orderID = trd.sellOrderID
+		case statusCloseBuyLimitPublished:
+			orderID = trd.buyOrderID
 		default:
 			continue
 		}
@@ -165,13 +513,27 @@ func (t *Trader) reconcileStorageTrades() bool {
 			continue
 		case connector.OrderStatusPartiallyFilled:
 			continue
+		case connector.OrderStatusCanceled:
+			// wind down keeps filled part of canceled open orders
+			if t.IsWindingDown() {
+				continue
+			}
+			isOk = false
+			logger.Error("order canceled on exchange")
 		case connector.OrderStatusExecuted:
-			if trd.status == statusBuyLimitPublished {
+			switch trd.status {
+			case statusBuyLimitPublished:
 				trd.openType = ord.orderType
 				trd.status = statusBuyLimitExecuted
-			} else {
+			case statusSellLimitPublished:
 				trd.closeType = ord.orderType
 				trd.status = statusSellLimitExecuted
+			case statusOpenSellLimitPublished:
+				trd.openType = ord.orderType
+				trd.status = statusOpenSellLimitExecuted
+			case statusCloseBuyLimitPublished:
+				trd.closeType = ord.orderType
+				trd.status = statusCloseBuyLimitExecuted
 			}
 
 			logger = logger.WithField("dataupdatedtrade", fmt.Sprintf("%+v", trd))
@@ -183,6 +545,20 @@ func (t *Trader) reconcileStorageTrades() bool {
 				continue
 			}
 
+			eventType := event.TypeBuyExecuted
+			if ord.side == connector.OrderSideSell {
+				eventType = event.TypeSellExecuted
+			}
+			t.publishEvent(event.Event{
+				Type:    eventType,
+				TradeID: trd.id,
+				Status:  trd.status,
+				Side:    ord.side,
+				OrderID: ord.id,
+				Price:   ord.price,
+				Volume:  ord.volume,
+			})
+
 			logger.Debug("success reconcile trade")
 
 		default:
@@ -219,6 +595,14 @@ func (t *Trader) moveTradesFromExecutedOnNextStatus() bool {
 			if ok := t.changeTradeSellClose(trd); !ok {
 				isOk = false
 			}
+		case statusOpenSellLimitExecuted:
+			if ok := t.changeTradeSellBuy(trd); !ok {
+				isOk = false
+			}
+		case statusCloseBuyLimitExecuted:
+			if ok := t.changeTradeBuyClose(trd); !ok {
+				isOk = false
+			}
 		default:
 			continue
 		}
@@ -255,10 +639,8 @@ func (t *Trader) changeTradeSellClose(trd trade) bool {
 		WithField("datatradeid", trd.id)
 
 	// idempotent add trade balance history
-	// TODO use const for action, or make specific method
-	action := "CASHED_IN"
-	if err := t.addBalanceHistoryIfNotExists(trd, action); err != nil {
-		t.logger.Errorf("changeTradeSellClose: fail to addBalanceHistoryIfNotExists, err: %w", err)
+	if err := t.addBalanceHistoryIfNotExists(trd, balanceActionCashedIn); err != nil {
+		logger.WithError(err).Error("changeTradeSellClose: fail to addBalanceHistoryIfNotExists")
 		return false
 	}
 
@@ -271,6 +653,47 @@ func (t *Trader) changeTradeSellClose(trd trade) bool {
 		logger.WithError(err).Error("changeTradeSellClose: fail update trade")
 		return false
 	}
+	t.publishTradeClosed(trd)
+
+	return true
+}
+
+func (t *Trader) changeTradeSellBuy(trd trade) bool {
+	logger := t.logger.
+		WithField("datatrade", fmt.Sprintf("%+v", trd)).
+		WithField("datatradeid", trd.id)
+
+	// fees of executed open order are known now, buy back what was really earned
+	trd.closeBaseVolume = t.closeBaseVolume(trd.openBasePrice, trd.closeBasePrice, trd.baseVolume, trd.openType)
+	trd.status = statusCloseBuyLimit
+
+	if err := t.storer.UpdateTrade(castToStorageTrade(trd)); err != nil {
+		logger.WithError(err).Error("changeTradeSellBuy: fail update trade")
+		return false
+	}
+
+	return true
+}
+
+func (t *Trader) changeTradeBuyClose(trd trade) bool {
+	logger := t.logger.
+		WithField("datatrade", fmt.Sprintf("%+v", trd)).
+		WithField("datatradeid", trd.id)
+
+	// idempotent add trade balance history
+	if err := t.addBalanceHistoryIfNotExists(trd, balanceActionCashedIn); err != nil {
+		logger.WithError(err).Error("changeTradeBuyClose: fail to addBalanceHistoryIfNotExists")
+		return false
+	}
+
+	trd.closedAt = time.Now().UTC()
+	trd.status = statusClosed
+
+	if err := t.storer.UpdateTrade(castToStorageTrade(trd)); err != nil {
+		logger.WithError(err).Error("changeTradeBuyClose: fail update trade")
+		return false
+	}
+	t.publishTradeClosed(trd)
 
 	return true
 }
@@ -289,24 +712,29 @@ func (t *Trader) addBalanceHistoryIfNotExists(trd trade, action string) error {
 		}
 	}
 
-	prevBalance, err := t.latestBalanceHistory()
-	if err != nil {
-		return fmt.Errorf("addBalanceHistoryIfNotExists: fail fetch latestBalance, err: %w", err)
-	}
-
-	netProfit := t.tradeNetProfit(trd)
-
-	balance := balanceHistory{
-		appID:           t.appID,
-		action:          "CASHED_IN",
-		quoteVolume:     netProfit,
-		totalNetIncome:  prevBalance.totalNetIncome + netProfit,
-		totalReinvested: prevBalance.totalReinvested,
-		internalTradeID: trd.id,
-		createdAt:       time.Now().UTC(),
-	}
+	err = t.appendBalanceHistory(func(prevBalance balanceHistory) (balanceHistory, bool, error) {
+		balance := prevBalance
+		balance.appID = t.appID
+		balance.action = action
+		balance.quoteVolume = 0
+		balance.baseVolume = 0
+		balance.internalTradeID = trd.id
+		balance.createdAt = time.Now().UTC()
+
+		// sell/buy grids earn base, buy/sell grids earn quote
+		if t.direction == DirectionSellBuy {
+			netBaseProfit := t.tradeNetBaseProfit(trd)
+			balance.baseVolume = netBaseProfit
+			balance.totalBaseNetIncome = prevBalance.totalBaseNetIncome + netBaseProfit
+		} else {
+			netProfit := t.tradeNetProfit(trd)
+			balance.quoteVolume = netProfit
+			balance.totalNetIncome = prevBalance.totalNetIncome + netProfit
+		}
 
-	if err := t.storer.AddBalanceHistory(t.appID, castToStorageBalanceHistory(balance)); err != nil {
+		return balance, true, nil
+	})
+	if err != nil {
 		return fmt.Errorf("addBalanceHistoryIfNotExists: fail to store balanceHistory, err: %w", err)
 	}
 
@@ -330,6 +758,98 @@ func (t *Trader) tradeNetProfit(trd trade) float64 {
 	return closeVolume - openVolume - openFees - closeFees
 }
 
+// reserveProfit keeps skimPercent of total net income reserved for withdraw, action is idempotent
+func (t *Trader) reserveProfit() bool {
+	if t.skimPercent <= 0 {
+		return true
+	}
+
+	err := t.appendBalanceHistory(func(latest balanceHistory) (balanceHistory, bool, error) {
+		toReserve := t.profitToReserve(latest)
+		if toReserve <= 0 {
+			return balanceHistory{}, false, nil
+		}
+
+		balance := latest
+		balance.appID = t.appID
+		balance.action = balanceActionReserve
+		balance.quoteVolume = toReserve
+		balance.baseVolume = 0
+		balance.totalReserved = latest.totalReserved + toReserve
+		balance.internalTradeID = 0
+		balance.createdAt = time.Now().UTC()
+
+		return balance, true, nil
+	})
+	if err != nil {
+		t.logger.WithError(err).Error("reserveProfit: fail store balance history")
+		return false
+	}
+
+	return true
+}
+
+// profitToReserve returns skim share of net income not reserved yet, capped at
+// income not already reinvested by compounding as it is committed to grid volume
+func (t *Trader) profitToReserve(latest balanceHistory) float64 {
+	toReserve := latest.totalNetIncome*t.skimPercent/100 - latest.totalReserved
+	notAllocated := latest.totalNetIncome - latest.totalReinvested - latest.totalReserved
+
+	// storage keeps 10 decimals, ignore differences caused by rounding
+	return decimal.ToFixed(math.Min(toReserve, notAllocated), 8)
+}
+
+// tradeNetBaseProfit returns base earned by a sell/buy trade, quote fees are paid on sell, base fees on buy back
+func (t *Trader) tradeNetBaseProfit(trd trade) float64 {
+	closeFees := (t.marketOrderFees / 100) * trd.closeBaseVolume
+	if trd.closeType == "LIMIT" {
+		closeFees = (t.limitOrderFees / 100) * trd.closeBaseVolume
+	}
+
+	return trd.closeBaseVolume - closeFees - trd.baseVolume
+}
+
+// closeBaseVolume returns base volume that can be bought back at closePrice
+// with the quote obtained (fees deducted) by selling baseVolume at openPrice,
+// highest fees are assumed while open order type is not known yet
+func (t *Trader) closeBaseVolume(openPrice, closePrice, baseVolume float64, openType string) float64 {
+	fees := math.Max(t.limitOrderFees, t.marketOrderFees)
+	switch openType {
+	case connector.OrderTypeLimit:
+		fees = t.limitOrderFees
+	case connector.OrderTypeMarket:
+		fees = t.marketOrderFees
+	}
+
+	quoteVolume := openPrice * baseVolume
+	quoteVolume = quoteVolume - (fees/100)*quoteVolume
+
+	if t.baseLotTick <= 0 {
+		return quoteVolume / closePrice
+	}
+
+	// round down so buy back order never needs more quote than sell order provided
+	return decimal.FloorToTick(quoteVolume/closePrice, t.baseLotTick)
+}
+
+// validateCloseOrder checks close order of a new trade against exchange lot and notional minimums
+func (t *Trader) validateCloseOrder(trd storage.Trade) error {
+	closeVolume := trd.BaseVolume
+	if t.direction == DirectionSellBuy {
+		closeVolume = trd.CloseBaseVolume
+	}
+
+	if closeVolume <= 0 || closeVolume < t.baseLotMin {
+		return fmt.Errorf("close volume %v is lower than min lot %v", closeVolume, t.baseLotMin)
+	}
+
+	if notional := trd.CloseBasePrice * closeVolume; notional < t.quoteMinVolume {
+		return fmt.Errorf("close notional %v is lower than min notional %v", notional, t.quoteMinVolume)
+	}
+
+	return nil
+}
+
 func (t *Trader) addMissingTrades() bool {
 	startTimeMs := time.Now().UnixNano() / int64(time.Millisecond)
 
@@ -340,6 +860,14 @@ func (t *Trader) addMissingTrades() bool {
 		return false
 	}
 
+	budget, err := t.capitalBudget(trades)
+	if err != nil {
+		t.logger.WithError(err).Error("addMissingTrades: fail calculate capital budget")
+		return false
+	}
+	lockedCapital, plannedCapital := usedCapital(trades)
+	usedQuote := lockedCapital + plannedCapital
+
 	isOk := true
 
 	// TODO refactor this, split in methods
@@ -373,6 +901,29 @@ func (t *Trader) addMissingTrades() bool {
 			CreatedAt:      time.Now().UTC(),
 		}
 
+		if t.direction == DirectionSellBuy {
+			trd.CloseBaseVolume = t.closeBaseVolume(trd.OpenBasePrice, trd.CloseBasePrice, trd.BaseVolume, "")
+			trd.Status = statusOpenSellLimit
+		}
+
+		if err := t.validateCloseOrder(trd); err != nil {
+			logger.WithError(err).Error("addMissingTrades: close order would be rejected by exchange, skip create")
+			isOk = false
+			continue
+		}
+
+		if budget > 0 && t.direction == DirectionBuySell {
+			if usedQuote+s*volume > budget {
+				labels := prometheus.Labels{"appid": strconv.Itoa(t.appID), "action": overBudgetActionCreate}
+				metricOverBudgetTrades.With(labels).Inc()
+				logger.WithField("budget", budget).
+					WithField("usedquote", usedQuote).
+					Warn("addMissingTrades: trade exceeds capital budget, skip create")
+				continue
+			}
+			usedQuote += s * volume
+		}
+
 		logger.WithField("datatrade", fmt.Sprintf("%+v", trd))
 
 		id, err := t.storer.AddTrade(trd)
@@ -381,6 +932,13 @@ func (t *Trader) addMissingTrades() bool {
 			isOk = false
 			continue
 		}
+		t.publishEvent(event.Event{
+			Type:    event.TypeTradeCreated,
+			TradeID: id,
+			Status:  trd.Status,
+			Price:   trd.OpenBasePrice,
+			Volume:  trd.BaseVolume,
+		})
 
 		// only if exists compound
 		if quoteCompounded <= 0 {
@@ -389,28 +947,37 @@ func (t *Trader) addMissingTrades() bool {
 
 		// TODO major issue
 		// if this fail, volume will be added again on next trade
-		latestBh, err := t.storer.LatestBalanceHistory(t.appID)
-		if err != nil {
-			logger.WithError(err).Error("addMissingTrades: fail get LatestBalanceHistory")
-			isOk = false
-			continue
-		}
+		err = t.appendBalanceHistory(func(latest balanceHistory) (balanceHistory, bool, error) {
+			bh := latest
+			bh.appID = t.appID
+			bh.action = balanceActionReinvest
+			bh.quoteVolume = 0
+			bh.baseVolume = 0
+			bh.internalTradeID = id
+			bh.createdAt = time.Now().UTC()
+
+			// compounded volume is expressed in the asset the grid earns
+			if t.direction == DirectionSellBuy {
+				bh.baseVolume = quoteCompounded
+				bh.totalBaseReinvested = latest.totalBaseReinvested + quoteCompounded
+			} else {
+				bh.quoteVolume = quoteCompounded
+				bh.totalReinvested = latest.totalReinvested + quoteCompounded
+			}
 
-		bh := balanceHistory{
-			appID:           t.appID,
-			action:          "REINVEST",
-			quoteVolume:     quoteCompounded,
-			totalNetIncome:  latestBh.TotalNetIncome,
-			totalReinvested: latestBh.TotalReinvested + quoteCompounded,
-			internalTradeID: id,
-			createdAt:       time.Now().UTC(),
-		}
-		err = t.storer.AddBalanceHistory(t.appID, castToStorageBalanceHistory(bh))
+			return bh, true, nil
+		})
 		if err != nil {
 			logger.WithError(err).Error("addMissingTrades: fail insert reinvest balance history")
 			isOk = false
 			continue
 		}
+		t.publishEvent(event.Event{
+			Type:    event.TypeReinvest,
+			TradeID: id,
+			Amount:  quoteCompounded,
+			Asset:   t.profitAsset(),
+		})
 	}
 
 	labels := prometheus.Labels{"appid": strconv.Itoa(t.appID)}
@@ -441,6 +1008,10 @@ func (t *Trader) markForPublishUnPublish() bool {
 			trd.status = statusBuyLimitWantsPublish
 		case statusSellLimit:
 			trd.status = statusSellLimitWantsPublish
+		case statusOpenSellLimit:
+			trd.status = statusOpenSellLimitWantsPublish
+		case statusCloseBuyLimit:
+			trd.status = statusCloseBuyLimitWantsPublish
 		default:
 			continue
 		}
@@ -467,18 +1038,48 @@ func (t *Trader) reconcileFromStorageToExchange() bool {
 		return false
 	}
 
+	// free quote is fetched once and consumed locally by each buy published on this run,
+	// buys also reserve it on connector as other apps of the account spend the same balance
+	balance, err := t.connector.Balance(t.quote)
+	if err != nil {
+		t.logger.WithError(err).Error("reconcileFromStorageToExchange: fail fetch quote balance")
+		return false
+	}
+	funds := quoteFunds{
+		free: balance.Free,
+	}
+
+	budget, err := t.capitalBudget(trades)
+	if err != nil {
+		t.logger.WithError(err).Error("reconcileFromStorageToExchange: fail calculate capital budget")
+		return false
+	}
+	if budget > 0 && t.direction == DirectionBuySell {
+		lockedCapital, _ := usedCapital(trades)
+		funds.hasBudget = true
+		funds.budgetLeft = budget - lockedCapital
+	}
+
 	isOk := true
 
 	for _, trd := range trades {
 		switch trd.status {
 		case statusBuyLimitWantsPublish:
-			if ok := t.publishBuyLimitOrder(trd); !ok {
+			if ok := t.publishBuyLimitOrder(trd, &funds); !ok {
 				isOk = false
 			}
 		case statusSellLimitWantsPublish:
 			if ok := t.publishSellLimitOrder(trd); !ok {
 				isOk = false
 			}
+		case statusOpenSellLimitWantsPublish:
+			if ok := t.publishOpenSellLimitOrder(trd); !ok {
+				isOk = false
+			}
+		case statusCloseBuyLimitWantsPublish:
+			if ok := t.publishCloseBuyLimitOrder(trd, &funds.free); !ok {
+				isOk = false
+			}
 		default:
 			continue
 		}
@@ -491,8 +1092,66 @@ func (t *Trader) reconcileFromStorageToExchange() bool {
 	return isOk
 }
 
-func (t *Trader) publishBuyLimitOrder(trd trade) bool {
+// fundedBuyVolume returns volume that can be bought with freeQuote, 0 when
+// shrunk volume is below exchange min lot or min notional
+func (t *Trader) fundedBuyVolume(price, volume, freeQuote float64) float64 {
+	if price*volume <= freeQuote {
+		return volume
+	}
+
+	affordable := freeQuote / price
+	if t.baseLotTick > 0 {
+		affordable = decimal.FloorToTick(affordable, t.baseLotTick)
+	}
+
+	if affordable <= 0 || affordable < t.baseLotMin || affordable*price < t.quoteMinVolume {
+		return 0
+	}
+
+	return affordable
+}
+
+// quoteFunds tracks quote that can still be spent by buys published on a trader run
+type quoteFunds struct {
+	free       float64
+	hasBudget  bool
+	budgetLeft float64
+}
+
+// publishBuyLimitOrder skips trades that exceed capital budget, skips or shrinks trades that free quote can not fund
+func (t *Trader) publishBuyLimitOrder(trd trade, funds *quoteFunds) bool {
 	logger := t.logger.WithField("datatrade", fmt.Sprintf("%+v", trd))
+	labels := prometheus.Labels{"appid": strconv.Itoa(t.appID)}
+
+	if funds.hasBudget && trd.openBasePrice*trd.baseVolume > funds.budgetLeft {
+		labels["action"] = overBudgetActionPublish
+		metricOverBudgetTrades.With(labels).Inc()
+		logger.WithField("budgetleft", funds.budgetLeft).Warn("publishBuyLimitOrder: trade exceeds capital budget, skip publish")
+		return true
+	}
+
+	volume := t.fundedBuyVolume(trd.openBasePrice, trd.baseVolume, funds.free)
+	if volume == 0 {
+		labels["action"] = underfundedActionSkip
+		metricUnderfundedTrades.With(labels).Inc()
+		logger.WithField("freequote", funds.free).Warn("publishBuyLimitOrder: underfunded trade, skip publish")
+		return true
+	}
+
+	if volume < trd.baseVolume {
+		labels["action"] = underfundedActionShrink
+		metricUnderfundedTrades.With(labels).Inc()
+		logger.WithField("freequote", funds.free).Warnf("publishBuyLimitOrder: underfunded trade, shrink volume to: %v", volume)
+	}
+
+	// connector balance is shared by all apps of the account
+	quoteVolume := trd.openBasePrice * volume
+	if !t.connector.ReserveBalance(t.quote, quoteVolume) {
+		labels["action"] = underfundedActionSkip
+		metricUnderfundedTrades.With(labels).Inc()
+		logger.Warn("publishBuyLimitOrder: free quote used by another app, skip publish")
+		return true
+	}
 
 	ord := connector.Order{
 		Base:      t.base,
@@ -500,17 +1159,26 @@ func (t *Trader) publishBuyLimitOrder(trd trade) bool {
 		OrderType: connector.OrderTypeLimit,
 		Side:      connector.OrderSideBuy,
 		Price:     trd.openBasePrice,
-		Volume:    trd.baseVolume,
+		Volume:    volume,
 	}
 
 	logger.WithField("dataorder", fmt.Sprintf("%+v", ord))
 
 	orderID, err := t.connector.AddOrder(t.appID, ord)
 	if err != nil {
+		// quote was not spent, other apps of the account can use it
+		t.connector.ReleaseBalance(t.quote, quoteVolume)
 		logger.WithError(err).Error("publishBuyLimitOrder: fail add exchange order")
+		t.publishOrderFailed(trd, ord, err)
 		return false
 	}
 
+	// shrunk volume is kept only once the order is placed
+	trd.baseVolume = volume
+
+	funds.free -= trd.openBasePrice * trd.baseVolume
+	funds.budgetLeft -= trd.openBasePrice * trd.baseVolume
+
 	trd.buyOrderID = orderID
 	trd.status = statusBuyLimitPublished
 	logger.WithField("dataupdatedtrade", fmt.Sprintf("%+v", trd))
@@ -519,6 +1187,7 @@ func (t *Trader) publishBuyLimitOrder(trd trade) bool {
 		logger.WithError(err).Error("publishBuyLimitOrder: fail update trade")
 		return false
 	}
+	t.publishOrderPublished(trd, ord, orderID)
 
 	return true
 }
@@ -540,6 +1209,7 @@ func (t *Trader) publishSellLimitOrder(trd trade) bool {
 	orderID, err := t.connector.AddOrder(t.appID, ord)
 	if err != nil {
 		logger.WithError(err).Error("publishSellLimitOrder: fail add exchange order")
+		t.publishOrderFailed(trd, ord, err)
 		return false
 	}
 
@@ -551,10 +1221,192 @@ func (t *Trader) publishSellLimitOrder(trd trade) bool {
 		logger.WithError(err).Error("publishSellLimitOrder: fail update trade")
 		return false
 	}
+	t.publishOrderPublished(trd, ord, orderID)
+
+	return true
+}
+
+func (t *Trader) publishOpenSellLimitOrder(trd trade) bool {
+	logger := t.logger.WithField("datatrade", fmt.Sprintf("%+v", trd))
+
+	ord := connector.Order{
+		Base:      t.base,
+		Quote:     t.quote,
+		OrderType: connector.OrderTypeLimit,
+		Side:      connector.OrderSideSell,
+		Price:     trd.openBasePrice,
+		Volume:    trd.baseVolume,
+	}
+
+	orderID, err := t.connector.AddOrder(t.appID, ord)
+	if err != nil {
+		logger.WithError(err).Error("publishOpenSellLimitOrder: fail add exchange order")
+		t.publishOrderFailed(trd, ord, err)
+		return false
+	}
+
+	trd.sellOrderID = orderID
+	trd.status = statusOpenSellLimitPublished
+
+	if err := t.storer.UpdateTrade(castToStorageTrade(trd)); err != nil {
+		logger.WithError(err).Error("publishOpenSellLimitOrder: fail update trade")
+		return false
+	}
+	t.publishOrderPublished(trd, ord, orderID)
+
+	return true
+}
+
+// publishCloseBuyLimitOrder skips trades that freeQuote can not fund, close volume is never shrunk
+func (t *Trader) publishCloseBuyLimitOrder(trd trade, freeQuote *float64) bool {
+	logger := t.logger.WithField("datatrade", fmt.Sprintf("%+v", trd))
+
+	if trd.closeBasePrice*trd.closeBaseVolume > *freeQuote {
+		labels := prometheus.Labels{"appid": strconv.Itoa(t.appID), "action": underfundedActionSkip}
+		metricUnderfundedTrades.With(labels).Inc()
+		logger.WithField("freequote", *freeQuote).Warn("publishCloseBuyLimitOrder: underfunded trade, skip publish")
+		return true
+	}
+
+	// connector balance is shared by all apps of the account
+	if !t.connector.ReserveBalance(t.quote, trd.closeBasePrice*trd.closeBaseVolume) {
+		labels := prometheus.Labels{"appid": strconv.Itoa(t.appID), "action": underfundedActionSkip}
+		metricUnderfundedTrades.With(labels).Inc()
+		logger.Warn("publishCloseBuyLimitOrder: free quote used by another app, skip publish")
+		return true
+	}
+
+	ord := connector.Order{
+		Base:      t.base,
+		Quote:     t.quote,
+		OrderType: connector.OrderTypeLimit,
+		Side:      connector.OrderSideBuy,
+		Price:     trd.closeBasePrice,
+		Volume:    trd.closeBaseVolume,
+	}
+
+	orderID, err := t.connector.AddOrder(t.appID, ord)
+	if err != nil {
+		logger.WithError(err).Error("publishCloseBuyLimitOrder: fail add exchange order")
+		t.publishOrderFailed(trd, ord, err)
+		return false
+	}
+
+	*freeQuote -= trd.closeBasePrice * trd.closeBaseVolume
+
+	trd.buyOrderID = orderID
+	trd.status = statusCloseBuyLimitPublished
+
+	if err := t.storer.UpdateTrade(castToStorageTrade(trd)); err != nil {
+		logger.WithError(err).Error("publishCloseBuyLimitOrder: fail update trade")
+		return false
+	}
+	t.publishOrderPublished(trd, ord, orderID)
 
 	return true
 }
 
+// capitalBudget returns quote capital app is allowed to use, 0 means no limit
+// absolute budget has priority over percent of account quote balance, percent
+// budget follows account balance synced by connector so deposits and withdrawals
+// are applied, quote already turned into base by filled buys is counted back
+func (t *Trader) capitalBudget(trades []trade) (float64, error) {
+	if t.quoteBudget > 0 {
+		return t.quoteBudget, nil
+	}
+
+	if t.quotePercentUse <= 0 {
+		return 0, nil
+	}
+
+	balance, err := t.connector.Balance(t.quote)
+	if err != nil {
+		return 0, fmt.Errorf("capitalBudget: fail fetch quote balance, err: %w", err)
+	}
+
+	// quote already converted to base by app trades belongs to the account value
+	return (balance.Free + balance.Locked + heldCapital(trades)) * t.quotePercentUse / 100, nil
+}
+
+// heldCapital returns quote spent on base still held by trades, valued at open price
+func heldCapital(trades []trade) float64 {
+	var held float64
+
+	for _, trd := range trades {
+		switch trd.status {
+		case statusBuyLimitExecuted,
+			statusSellLimit,
+			statusSellLimitWantsPublish,
+			statusSellLimitPublished,
+			statusSellLimitExecuted:
+			held += trd.openBasePrice * trd.baseVolume
+		}
+	}
+
+	return held
+}
+
+// usedCapital returns quote locked in published buys or held as base by trades
+// and quote planned by buys not published yet, it applies to BUY_SELL apps
+func usedCapital(trades []trade) (float64, float64) {
+	var locked, planned float64
+
+	for _, trd := range trades {
+		switch trd.status {
+		case statusBuyLimit, statusBuyLimitWantsPublish:
+			planned += trd.openBasePrice * trd.baseVolume
+		default:
+			locked += lockedCapital(DirectionBuySell, trd)
+		}
+	}
+
+	return locked, planned
+}
+
+// LockedCapital returns capital committed on exchange by active trades, quote
+// for BUY_SELL apps and base for SELL_BUY apps, trades without order are not
+// counted
+func LockedCapital(direction string, trades []storage.Trade) float64 {
+	var locked float64
+
+	for _, st := range trades {
+		locked += lockedCapital(direction, castStorageTrade(st))
+	}
+
+	return locked
+}
+
+// lockedCapital returns quote locked in published buy or held as base by a
+// BUY_SELL trade, or base locked in published sell or sold and not bought back
+// by a SELL_BUY trade
+func lockedCapital(direction string, trd trade) float64 {
+	if direction == DirectionSellBuy {
+		switch trd.status {
+		case statusOpenSellLimitPublished,
+			statusOpenSellLimitExecuted,
+			statusCloseBuyLimit,
+			statusCloseBuyLimitWantsPublish,
+			statusCloseBuyLimitPublished,
+			statusCloseBuyLimitExecuted:
+			return trd.baseVolume
+		}
+
+		return 0
+	}
+
+	switch trd.status {
+	case statusBuyLimitPublished,
+		statusBuyLimitExecuted,
+		statusSellLimit,
+		statusSellLimitWantsPublish,
+		statusSellLimitPublished,
+		statusSellLimitExecuted:
+		return trd.openBasePrice * trd.baseVolume
+	}
+
+	return 0
+}
+
 type trade struct {
 	id                   int
 	appID                int
@@ -563,6 +1415,7 @@ type trade struct {
 	openType             string
 	closeType            string
 	baseVolume           float64
+	closeBaseVolume      float64
 	buyOrderID           string
 	sellOrderID          string
 	status               string
@@ -599,20 +1452,84 @@ type order struct {
 }
 
 type balanceHistory struct {
-	appID           int
-	action          string
-	quoteVolume     float64
-	totalNetIncome  float64
-	totalReinvested float64
-	internalTradeID int
-	createdAt       time.Time
+	appID               int
+	action              string
+	quoteVolume         float64
+	totalNetIncome      float64
+	totalReinvested     float64
+	totalReserved       float64
+	totalWithdrawn      float64
+	baseVolume          float64
+	totalBaseNetIncome  float64
+	totalBaseReinvested float64
+	internalTradeID     int
+	createdAt           time.Time
 }
 
-func (t *Trader) latestBalanceHistory() (balanceHistory, error) {
-	prevBalance, err := t.storer.LatestBalanceHistory(t.appID)
-	if err != nil {
-		return balanceHistory{}, err
+// appendBalanceHistory adds the balance row built by next from latest app balance
+func (t *Trader) appendBalanceHistory(next func(latest balanceHistory) (balanceHistory, bool, error)) error {
+	return t.storer.AppendBalanceHistory(t.appID, func(latest storage.BalanceHistory) (storage.BalanceHistory, bool, error) {
+		balance, ok, err := next(castStorageBalanceHistory(latest))
+		return castToStorageBalanceHistory(balance), ok, err
+	})
+}
+
+// publishEvent sends a trade domain event when an events publisher is set
+func (t *Trader) publishEvent(e event.Event) {
+	if t.events == nil {
+		return
+	}
+
+	e.AppID = t.appID
+	t.events.Publish(e)
+}
+
+func (t *Trader) publishOrderPublished(trd trade, ord connector.Order, orderID string) {
+	t.publishEvent(event.Event{
+		Type:    event.TypeOrderPublished,
+		TradeID: trd.id,
+		Status:  trd.status,
+		Side:    ord.Side,
+		OrderID: orderID,
+		Price:   ord.Price,
+		Volume:  ord.Volume,
+	})
+}
+
+func (t *Trader) publishOrderFailed(trd trade, ord connector.Order, err error) {
+	t.publishEvent(event.Event{
+		Type:    event.TypeOrderFailed,
+		TradeID: trd.id,
+		Status:  trd.status,
+		Side:    ord.Side,
+		Price:   ord.Price,
+		Volume:  ord.Volume,
+		Error:   err.Error(),
+	})
+}
+
+func (t *Trader) publishTradeClosed(trd trade) {
+	amount := t.tradeNetProfit(trd)
+	if t.direction == DirectionSellBuy {
+		amount = t.tradeNetBaseProfit(trd)
+	}
+
+	t.publishEvent(event.Event{
+		Type:    event.TypeTradeClosed,
+		TradeID: trd.id,
+		Status:  trd.status,
+		Price:   trd.closeBasePrice,
+		Volume:  trd.baseVolume,
+		Amount:  amount,
+		Asset:   t.profitAsset(),
+	})
+}
+
+// profitAsset is the asset the grid earns
+func (t *Trader) profitAsset() string {
+	if t.direction == DirectionSellBuy {
+		return t.base
 	}
 
-	return castStorageBalanceHistory(prevBalance), nil
+	return t.quote
 }
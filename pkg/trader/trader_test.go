@@ -0,0 +1,290 @@
+package trader
+
+import (
+	"bwd/pkg/connector"
+	"bwd/pkg/storage"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeStorer keeps latest balance history in memory, methods not overridden panic
+type fakeStorer struct {
+	storage.Storer
+	balance storage.BalanceHistory
+	updated []storage.Trade
+}
+
+func (f *fakeStorer) UpdateTrade(trade storage.Trade) error {
+	f.updated = append(f.updated, trade)
+	return nil
+}
+
+func (f *fakeStorer) AppendBalanceHistory(appID int, next func(latest storage.BalanceHistory) (storage.BalanceHistory, bool, error)) error {
+	balance, ok, err := next(f.balance)
+	if err != nil {
+		return err
+	}
+	if ok {
+		f.balance = balance
+	}
+
+	return nil
+}
+
+// fakeConnector records reservations and orders, methods not overridden panic
+type fakeConnector struct {
+	connector.Connector
+	addErr   error
+	reserved []float64
+	released []float64
+	orders   []connector.Order
+	balance  connector.Balance
+}
+
+func (f *fakeConnector) Balance(asset string) (connector.Balance, error) {
+	return f.balance, nil
+}
+
+func (f *fakeConnector) ReserveBalance(asset string, volume float64) bool {
+	f.reserved = append(f.reserved, volume)
+	return true
+}
+
+func (f *fakeConnector) ReleaseBalance(asset string, volume float64) {
+	f.released = append(f.released, volume)
+}
+
+func (f *fakeConnector) AddOrder(appID int, order connector.Order) (string, error) {
+	if f.addErr != nil {
+		return "", f.addErr
+	}
+	f.orders = append(f.orders, order)
+	return "order-1", nil
+}
+
+func testLogger() logrus.FieldLogger {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	return logger
+}
+
+func TestLockedCapital(t *testing.T) {
+	trades := []storage.Trade{
+		{Status: statusBuyLimit, OpenBasePrice: 100, BaseVolume: 1},
+		{Status: statusBuyLimitWantsPublish, OpenBasePrice: 100, BaseVolume: 1},
+		{Status: statusBuyLimitPublished, OpenBasePrice: 100, BaseVolume: 1},
+		{Status: statusSellLimitPublished, OpenBasePrice: 50, BaseVolume: 2},
+		{Status: statusOpenSellLimit, OpenBasePrice: 100, BaseVolume: 0.5},
+		{Status: statusOpenSellLimitWantsPublish, OpenBasePrice: 100, BaseVolume: 0.5},
+		{Status: statusOpenSellLimitPublished, OpenBasePrice: 100, BaseVolume: 0.5},
+		{Status: statusCloseBuyLimitPublished, OpenBasePrice: 100, BaseVolume: 0.25},
+	}
+
+	tests := []struct {
+		name      string
+		direction string
+		trades    []storage.Trade
+		want      float64
+	}{
+		{name: "no trades", direction: DirectionBuySell, want: 0},
+		{name: "buy sell counts quote of trades with orders or base held", direction: DirectionBuySell, trades: trades, want: 200},
+		{name: "sell buy counts base of trades with orders or quote held", direction: DirectionSellBuy, trades: trades, want: 0.75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LockedCapital(tt.direction, tt.trades); got != tt.want {
+				t.Errorf("expected: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUsedCapital(t *testing.T) {
+	trades := []trade{
+		{status: statusBuyLimit, openBasePrice: 100, baseVolume: 1},
+		{status: statusBuyLimitWantsPublish, openBasePrice: 100, baseVolume: 2},
+		{status: statusBuyLimitPublished, openBasePrice: 100, baseVolume: 1},
+		{status: statusBuyLimitExecuted, openBasePrice: 50, baseVolume: 1},
+		{status: statusClosed, openBasePrice: 100, baseVolume: 1},
+	}
+
+	locked, planned := usedCapital(trades)
+	if locked != 150 || planned != 300 {
+		t.Errorf("expected locked: 150 planned: 300, got locked: %v planned: %v", locked, planned)
+	}
+}
+
+func TestReserveProfitAfterReinvest(t *testing.T) {
+	storer := &fakeStorer{}
+	trd := New(&ConfigTrader{AppID: 1, SkimPercent: 50, Storer: storer}, testLogger())
+
+	// each step changes balance as trades close or compounding reinvests, then reserve runs
+	steps := []struct {
+		name         string
+		income       float64
+		reinvest     float64
+		wantReserved float64
+	}{
+		{name: "income reserved by skim share", income: 4, wantReserved: 2},
+		{name: "income reinvested before reserve", income: 10, reinvest: 11, wantReserved: 3},
+		{name: "reinvested income is not reserved again", wantReserved: 3},
+		{name: "new income tops up reserve", income: 10, wantReserved: 12},
+	}
+
+	for _, step := range steps {
+		storer.balance.TotalNetIncome += step.income
+		storer.balance.TotalReinvested += step.reinvest
+
+		if ok := trd.reserveProfit(); !ok {
+			t.Fatalf("%s: reserve fail", step.name)
+		}
+
+		b := storer.balance
+		if b.TotalReserved != step.wantReserved {
+			t.Errorf("%s: expected reserved: %v, got: %v", step.name, step.wantReserved, b.TotalReserved)
+		}
+		if b.TotalReserved+b.TotalReinvested > b.TotalNetIncome {
+			t.Errorf("%s: reserved: %v and reinvested: %v exceed net income: %v",
+				step.name, b.TotalReserved, b.TotalReinvested, b.TotalNetIncome)
+		}
+	}
+}
+
+func TestPublishBuyLimitOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		addErr       error
+		freeQuote    float64
+		wantOk       bool
+		wantReserved []float64
+		wantReleased []float64
+		// wantVolume of stored trade, 0 when trade should not be updated
+		wantVolume float64
+	}{
+		{
+			name:         "published",
+			freeQuote:    1000,
+			wantOk:       true,
+			wantReserved: []float64{200},
+			wantVolume:   2,
+		},
+		{
+			name:         "shrunk and published",
+			freeQuote:    150,
+			wantOk:       true,
+			wantReserved: []float64{150},
+			wantVolume:   1.5,
+		},
+		{
+			name:         "reservation released on exchange error",
+			addErr:       errors.New("exchange down"),
+			freeQuote:    1000,
+			wantReserved: []float64{200},
+			wantReleased: []float64{200},
+		},
+		{
+			name:         "shrunk volume not kept on exchange error",
+			addErr:       errors.New("exchange down"),
+			freeQuote:    150,
+			wantReserved: []float64{150},
+			wantReleased: []float64{150},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storer := &fakeStorer{}
+			conn := &fakeConnector{addErr: tt.addErr}
+			trd := New(&ConfigTrader{
+				AppID:       1,
+				Base:        "BTC",
+				Quote:       "USDT",
+				BaseLotTick: 0.1,
+				BaseLotMin:  0.1,
+				Storer:      storer,
+				Connector:   conn,
+			}, testLogger())
+
+			funds := &quoteFunds{free: tt.freeQuote}
+			open := trade{id: 7, openBasePrice: 100, baseVolume: 2, status: statusBuyLimitWantsPublish}
+
+			if ok := trd.publishBuyLimitOrder(open, funds); ok != tt.wantOk {
+				t.Fatalf("expected ok: %v, got: %v", tt.wantOk, ok)
+			}
+
+			if !floatsEqual(conn.reserved, tt.wantReserved) {
+				t.Errorf("expected reserved: %v, got: %v", tt.wantReserved, conn.reserved)
+			}
+			if !floatsEqual(conn.released, tt.wantReleased) {
+				t.Errorf("expected released: %v, got: %v", tt.wantReleased, conn.released)
+			}
+
+			if tt.wantVolume == 0 {
+				if len(storer.updated) != 0 {
+					t.Errorf("expected trade not updated, got: %+v", storer.updated)
+				}
+				return
+			}
+
+			if len(storer.updated) != 1 || storer.updated[0].BaseVolume != tt.wantVolume {
+				t.Fatalf("expected trade updated with volume: %v, got: %+v", tt.wantVolume, storer.updated)
+			}
+			if conn.orders[0].Volume != tt.wantVolume {
+				t.Errorf("expected order volume: %v, got: %v", tt.wantVolume, conn.orders[0].Volume)
+			}
+		})
+	}
+}
+
+func floatsEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCapitalBudgetFollowsBalance(t *testing.T) {
+	conn := &fakeConnector{}
+	trd := New(&ConfigTrader{AppID: 1, Quote: "USDT", QuotePercentUse: 50, Connector: conn}, testLogger())
+	held := []trade{{status: statusBuyLimitExecuted, openBasePrice: 100, baseVolume: 1}}
+
+	// each sync reports a new balance, budget is taken again
+	syncs := []struct {
+		name    string
+		balance connector.Balance
+		trades  []trade
+		want    float64
+	}{
+		{name: "first sync", balance: connector.Balance{Free: 800, Locked: 200}, want: 500},
+		{name: "buy filled, quote turned into base", balance: connector.Balance{Free: 800, Locked: 100}, trades: held, want: 500},
+		{name: "deposit", balance: connector.Balance{Free: 1800, Locked: 100}, trades: held, want: 1000},
+		{name: "withdrawal", balance: connector.Balance{Free: 300, Locked: 100}, trades: held, want: 250},
+	}
+
+	for _, sync := range syncs {
+		conn.balance = sync.balance
+		budget, err := trd.capitalBudget(sync.trades)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", sync.name, err)
+		}
+		if budget != sync.want {
+			t.Errorf("%s: expected budget: %v, got: %v", sync.name, sync.want, budget)
+		}
+	}
+
+	trd.quotePercentUse = 10
+	conn.balance = connector.Balance{Free: 1000}
+	if budget, _ := trd.capitalBudget(nil); budget != 100 {
+		t.Errorf("changed percent: expected budget: 100, got: %v", budget)
+	}
+}
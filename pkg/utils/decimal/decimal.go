@@ -0,0 +1,50 @@
+// Package decimal holds float helpers for exchange prices and volumes
+package decimal
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+func Round(num float64) int {
+	return int(num + math.Copysign(0.5, num))
+}
+
+func ToFixed(num float64, precision int) float64 {
+	output := math.Pow(10, float64(precision))
+	return float64(Round(num*output)) / output
+}
+
+// Precision returns the number of digits after .
+func Precision(f float64) int {
+	strVal := strconv.FormatFloat(f, 'f', -1, 64)
+	split := strings.Split(strVal, ".")
+
+	// digits after floating point
+	daf := 0
+	if len(split) > 1 {
+		daf = len(split[1])
+	}
+
+	return daf
+}
+
+// IsMultiple returns true if num is a multiple of tick (tolerates float errors)
+func IsMultiple(num, tick float64) bool {
+	if tick <= 0 {
+		return true
+	}
+	ticks := num / tick
+	return math.Abs(ticks-math.Round(ticks)) < 1e-6
+}
+
+// FloorToTick rounds num down to a multiple of tick, float errors like
+// 0.3/0.1 = 2.9999999999999996 do not lose a tick
+func FloorToTick(num, tick float64) float64 {
+	if tick <= 0 {
+		return num
+	}
+	ticks := math.Floor(ToFixed(num/tick, 8))
+	return ToFixed(ticks*tick, Precision(tick))
+}
@@ -0,0 +1,66 @@
+package decimal
+
+import "testing"
+
+func TestFloorToTick(t *testing.T) {
+	tests := []struct {
+		name string
+		num  float64
+		tick float64
+		want float64
+	}{
+		{name: "already on tick", num: 1.25, tick: 0.01, want: 1.25},
+		{name: "rounded down", num: 33.3333, tick: 0.01, want: 33.33},
+		{name: "float error keeps tick", num: 0.3, tick: 0.1, want: 0.3},
+		{name: "integer tick", num: 17.9, tick: 1, want: 17},
+		{name: "no tick", num: 1.23456, tick: 0, want: 1.23456},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FloorToTick(tt.num, tt.tick); got != tt.want {
+				t.Errorf("expected: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsMultiple(t *testing.T) {
+	tests := []struct {
+		name string
+		num  float64
+		tick float64
+		want bool
+	}{
+		{name: "multiple", num: 1.25, tick: 0.05, want: true},
+		{name: "float error tolerated", num: 0.3, tick: 0.1, want: true},
+		{name: "not multiple", num: 1.255, tick: 0.01, want: false},
+		{name: "no tick", num: 1.255, tick: 0, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsMultiple(tt.num, tt.tick); got != tt.want {
+				t.Errorf("expected: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	tests := []struct {
+		f    float64
+		want int
+	}{
+		{f: 1, want: 0},
+		{f: 0.01, want: 2},
+		{f: 0.00001, want: 5},
+		{f: 120.5, want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := Precision(tt.f); got != tt.want {
+			t.Errorf("precision of %v, expected: %d, got: %d", tt.f, tt.want, got)
+		}
+	}
+}
@@ -88,8 +88,12 @@ func getAndRegisterHistogramVec(ns, metricName string, labelNames []string) *pro
 	return histogram
 }
 
-func GetExporter(port string) {
+// GetExporter serves metrics and extra handlers, eg: health checks
+func GetExporter(port string, handlers map[string]http.Handler) error {
 	server := http.NewServeMux()
 	server.Handle("/metrics", promhttp.Handler())
-	http.ListenAndServe(fmt.Sprintf(":%s", port), server)
+	for pattern, h := range handlers {
+		server.Handle(pattern, h)
+	}
+	return http.ListenAndServe(fmt.Sprintf(":%s", port), server)
 }
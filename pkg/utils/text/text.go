@@ -0,0 +1,18 @@
+// Package text holds string helpers
+package text
+
+import "unicode/utf8"
+
+// Truncate keeps at most max runes of s, it never splits a multi byte character
+func Truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+
+	runes := []rune(s)
+	if max < 0 {
+		max = 0
+	}
+
+	return string(runes[:max])
+}
@@ -0,0 +1,35 @@
+package text
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{name: "shorter", s: "abc", max: 5, want: "abc"},
+		{name: "exact", s: "abc", max: 3, want: "abc"},
+		{name: "ascii cut", s: "abcdef", max: 3, want: "abc"},
+		{name: "multi byte kept whole", s: "prix 10€ atteint", max: 8, want: "prix 10€"},
+		{name: "emoji kept whole", s: "✅✅✅", max: 2, want: "✅✅"},
+		{name: "zero", s: "abc", max: 0, want: ""},
+		{name: "negative", s: "abc", max: -1, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.s, tt.max)
+			if got != tt.want {
+				t.Errorf("expected: %q, got: %q", tt.want, got)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("expected valid utf8, got: %q", got)
+			}
+		})
+	}
+}
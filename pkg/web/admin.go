@@ -0,0 +1,312 @@
+package web
+
+import (
+	"bwd/pkg/storage"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	auditActionAppCreate = "APP_CREATE"
+	auditActionAppStatus = "APP_STATUS"
+	auditActionAppGrid   = "APP_GRID"
+)
+
+// statuses that can be set through admin api, new apps are always INACTIVE
+var adminAppStatuses = map[string]bool{
+	"ACTIVE":       true,
+	"INACTIVE":     true,
+	"PAUSED":       true,
+	"WINDING_DOWN": true,
+}
+
+// maxBodyBytes limits admin request body size
+const maxBodyBytes = 64 << 10
+
+// audit records an admin change, change is already applied when audit fails
+func (s *Server) audit(actor, action string, appID int, details interface{}) {
+	detailsJSON, _ := json.Marshal(details)
+
+	entry := storage.AuditLog{
+		Actor:     actor,
+		Action:    action,
+		AppID:     appID,
+		Details:   string(detailsJSON),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.logger.WithField("actor", actor).WithField("appid", appID).WithField("dataaudit", entry.Details).Info(action)
+	if err := s.storer.AddAuditLog(entry); err != nil {
+		s.logger.WithError(err).WithField("actor", actor).WithField("appid", appID).Error("fail add audit log")
+	}
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid body, err: %s", err.Error())
+	}
+
+	return nil
+}
+
+// appRequest is app config sent by admin api
+type appRequest struct {
+	ID                 int     `json:"id"`
+	Exchange           string  `json:"exchange"`
+	AccountID          int     `json:"account_id"`
+	Base               string  `json:"base"`
+	Quote              string  `json:"quote"`
+	Interval           string  `json:"interval"`
+	MarketOrderFees    float64 `json:"market_order_fees"`
+	LimitOrderFees     float64 `json:"limit_order_fees"`
+	MinBasePrice       float64 `json:"min_base_price"`
+	MaxBasePrice       float64 `json:"max_base_price"`
+	StepQuoteVolume    float64 `json:"step_quote_volume"`
+	StepsType          string  `json:"steps_type"`
+	StepsDetails       string  `json:"steps_details"`
+	CompoundType       string  `json:"compound_type"`
+	CompoundDetails    string  `json:"compound_details"`
+	PublishOrderNumber int     `json:"publish_order_number"`
+	Direction          string  `json:"direction"`
+	SkimPercent        float64 `json:"skim_percent"`
+	QuoteBudget        float64 `json:"quote_budget"`
+	QuotePercentUse    float64 `json:"quote_percent_use"`
+	StopPolicy         string  `json:"stop_policy"`
+}
+
+// toApp converts request to an INACTIVE app, defaults match storage defaults
+func (req appRequest) toApp() (storage.App, error) {
+	if req.ID < 1 {
+		return storage.App{}, fmt.Errorf("id should be greater than 0")
+	}
+
+	interval, err := time.ParseDuration(req.Interval)
+	if err != nil {
+		return storage.App{}, fmt.Errorf("invalid interval: %s", req.Interval)
+	}
+
+	a := storage.App{
+		ID:                 req.ID,
+		Interval:           interval,
+		Exchange:           strings.ToUpper(req.Exchange),
+		AccountID:          req.AccountID,
+		MarketOrderFees:    req.MarketOrderFees,
+		LimitOrderFees:     req.LimitOrderFees,
+		Base:               strings.ToUpper(req.Base),
+		Quote:              strings.ToUpper(req.Quote),
+		QuotePercentUse:    req.QuotePercentUse,
+		QuoteBudget:        req.QuoteBudget,
+		MinBasePrice:       req.MinBasePrice,
+		MaxBasePrice:       req.MaxBasePrice,
+		StepQuoteVolume:    req.StepQuoteVolume,
+		StepsType:          req.StepsType,
+		StepsDetails:       req.StepsDetails,
+		CompoundType:       req.CompoundType,
+		CompoundDetails:    req.CompoundDetails,
+		PublishOrderNumber: req.PublishOrderNumber,
+		Direction:          req.Direction,
+		SkimPercent:        req.SkimPercent,
+		StopPolicy:         req.StopPolicy,
+		Status:             "INACTIVE",
+	}
+
+	if a.Direction == "" {
+		a.Direction = "BUY_SELL"
+	}
+	if a.StopPolicy == "" {
+		a.StopPolicy = "LEAVE"
+	}
+
+	return a, nil
+}
+
+// gridRequest edits grid parameters, missing fields are not changed
+type gridRequest struct {
+	MinBasePrice       *float64 `json:"min_base_price"`
+	MaxBasePrice       *float64 `json:"max_base_price"`
+	StepQuoteVolume    *float64 `json:"step_quote_volume"`
+	StepsType          *string  `json:"steps_type"`
+	StepsDetails       *string  `json:"steps_details"`
+	PublishOrderNumber *int     `json:"publish_order_number"`
+}
+
+func (req gridRequest) apply(a storage.App) storage.App {
+	if req.MinBasePrice != nil {
+		a.MinBasePrice = *req.MinBasePrice
+	}
+	if req.MaxBasePrice != nil {
+		a.MaxBasePrice = *req.MaxBasePrice
+	}
+	if req.StepQuoteVolume != nil {
+		a.StepQuoteVolume = *req.StepQuoteVolume
+	}
+	if req.StepsType != nil {
+		a.StepsType = *req.StepsType
+	}
+	if req.StepsDetails != nil {
+		a.StepsDetails = *req.StepsDetails
+	}
+	if req.PublishOrderNumber != nil {
+		a.PublishOrderNumber = *req.PublishOrderNumber
+	}
+
+	return a
+}
+
+type statusRequest struct {
+	Status string `json:"status"`
+}
+
+type validationResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+func (s *Server) validateApp(a storage.App) validationResponse {
+	if err := s.state.ValidateApp(a); err != nil {
+		return validationResponse{Error: err.Error()}
+	}
+
+	return validationResponse{Valid: true}
+}
+
+// handleCreateApp stores a new INACTIVE app, validation result is returned but
+// does not block creation, app account connector may not be running yet
+func (s *Server) handleCreateApp(w http.ResponseWriter, r *http.Request) {
+	var req appRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	appCfg, err := req.toApp()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, found, err := s.app(appCfg.ID)
+	if err != nil {
+		s.logger.WithError(err).WithField("appid", appCfg.ID).Error("fail fetch app")
+		writeError(w, http.StatusInternalServerError, "fail fetch app")
+		return
+	}
+	if found {
+		writeError(w, http.StatusConflict, "app id already exists")
+		return
+	}
+
+	if err := s.storer.AddApp(appCfg); err != nil {
+		s.logger.WithError(err).WithField("appid", appCfg.ID).Error("fail add app")
+		writeError(w, http.StatusInternalServerError, "fail add app")
+		return
+	}
+	s.audit(requestActor(r), auditActionAppCreate, appCfg.ID, req)
+
+	writeJSON(w, http.StatusCreated, struct {
+		App        appResponse        `json:"app"`
+		Validation validationResponse `json:"validation"`
+	}{
+		App:        s.newAppResponse(appCfg),
+		Validation: s.validateApp(appCfg),
+	})
+}
+
+// handleValidateApp validates an app config sent in body, nothing is stored
+func (s *Server) handleValidateApp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req appRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	appCfg, err := req.toApp()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.validateApp(appCfg))
+}
+
+// handleAppStatus changes app status, app is validated before activation
+func (s *Server) handleAppStatus(w http.ResponseWriter, r *http.Request, appCfg storage.App) {
+	var req statusRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := strings.ToUpper(req.Status)
+	if !adminAppStatuses[status] {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid status: %s", req.Status))
+		return
+	}
+
+	if status == "ACTIVE" {
+		if v := s.validateApp(appCfg); !v.Valid {
+			writeJSON(w, http.StatusUnprocessableEntity, v)
+			return
+		}
+	}
+
+	if err := s.storer.UpdateAppStatus(appCfg.ID, status); err != nil {
+		s.logger.WithError(err).WithField("appid", appCfg.ID).Error("fail update app status")
+		writeError(w, http.StatusInternalServerError, "fail update app status")
+		return
+	}
+	s.audit(requestActor(r), auditActionAppStatus, appCfg.ID, map[string]string{"from": appCfg.Status, "to": status})
+
+	appCfg.Status = status
+	writeJSON(w, http.StatusOK, s.newAppResponse(appCfg))
+}
+
+// handleAppGrid edits app grid parameters, invalid grids are rejected
+// running app is restarted by bwd with new grid
+func (s *Server) handleAppGrid(w http.ResponseWriter, r *http.Request, appCfg storage.App) {
+	var req gridRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated := req.apply(appCfg)
+	if v := s.validateApp(updated); !v.Valid {
+		writeJSON(w, http.StatusUnprocessableEntity, v)
+		return
+	}
+
+	if err := s.storer.UpdateApp(updated); err != nil {
+		s.logger.WithError(err).WithField("appid", appCfg.ID).Error("fail update app grid")
+		writeError(w, http.StatusInternalServerError, "fail update app grid")
+		return
+	}
+	s.audit(requestActor(r), auditActionAppGrid, appCfg.ID, map[string]interface{}{
+		"from": appGrid(appCfg),
+		"to":   appGrid(updated),
+	})
+
+	writeJSON(w, http.StatusOK, s.newAppResponse(updated))
+}
+
+// appGrid returns all grid parameters of app
+func appGrid(a storage.App) gridRequest {
+	return gridRequest{
+		MinBasePrice:       &a.MinBasePrice,
+		MaxBasePrice:       &a.MaxBasePrice,
+		StepQuoteVolume:    &a.StepQuoteVolume,
+		StepsType:          &a.StepsType,
+		StepsDetails:       &a.StepsDetails,
+		PublishOrderNumber: &a.PublishOrderNumber,
+	}
+}
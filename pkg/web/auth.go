@@ -0,0 +1,205 @@
+package web
+
+import (
+	"bwd/pkg/auth"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	auditActionAuthDenied = "AUTH_DENIED"
+	auditActionRead       = "API_READ"
+	// event stream ticket is used once, right after being issued
+	eventTicketTTL = 30 * time.Second
+)
+
+type principalKey struct{}
+
+// principal is the api token owner of a request
+type principal struct {
+	tokenID int
+	name    string
+	role    string
+}
+
+// eventTickets are short lived one time credentials for event stream,
+// browsers EventSource can not set headers so they are passed in url
+type eventTickets struct {
+	m sync.Mutex
+	// keyed by ticket hash
+	tickets map[string]eventTicket
+}
+
+type eventTicket struct {
+	principal principal
+	expiresAt time.Time
+}
+
+// issue returns a new ticket for principal
+func (t *eventTickets) issue(p principal, now time.Time) (string, error) {
+	ticket, hash, err := auth.GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("fail generate ticket, err: %w", err)
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	// expired tickets are dropped here, nothing else writes the map
+	for h, et := range t.tickets {
+		if now.After(et.expiresAt) {
+			delete(t.tickets, h)
+		}
+	}
+	t.tickets[hash] = eventTicket{principal: p, expiresAt: now.Add(eventTicketTTL)}
+
+	return ticket, nil
+}
+
+// redeem consumes ticket, a ticket is valid only once
+func (t *eventTickets) redeem(ticket string, now time.Time) (principal, error) {
+	hash := auth.HashToken(ticket)
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	et, ok := t.tickets[hash]
+	if !ok {
+		return principal{}, errors.New("unknown ticket")
+	}
+	delete(t.tickets, hash)
+
+	if now.After(et.expiresAt) {
+		return principal{}, errors.New("expired ticket")
+	}
+
+	return et.principal, nil
+}
+
+// isRead reports if request only reads data, event ticket does not change state
+func isRead(r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+
+	return r.Method == http.MethodPost && r.URL.Path == "/api/events/ticket"
+}
+
+// authorize requires a valid api token, read requests need READ_ONLY role
+// and all other requests need ADMIN role, every request is audited
+func (s *Server) authorize(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		required := auth.RoleAdmin
+		if isRead(r) {
+			required = auth.RoleReadOnly
+		}
+
+		p, err := s.authenticate(r)
+		if err != nil {
+			s.logger.WithError(err).WithField("path", r.URL.Path).WithField("remote", r.RemoteAddr).Warn("unauthenticated api request")
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		logger := s.logger.
+			WithField("actor", p.name).
+			WithField("method", r.Method).
+			WithField("path", r.URL.Path)
+
+		if !auth.Allows(p.role, required) {
+			logger.Warn("api request denied")
+			s.audit(p.name, auditActionAuthDenied, 0, map[string]string{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"role":   p.role,
+			})
+			writeError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+
+		// admin writes are audited by their handlers with request details
+		if required == auth.RoleReadOnly {
+			s.audit(p.name, auditActionRead, 0, map[string]string{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"query":  redactedQuery(r),
+			})
+		} else {
+			logger.Info("authenticated api request")
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	}
+}
+
+// authenticate resolves bearer token, event stream also accepts a ticket query
+// param issued by /api/events/ticket, api tokens are never read from url
+func (s *Server) authenticate(r *http.Request) (principal, error) {
+	token := ""
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token = strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	if token == "" && r.URL.Path == "/api/events" {
+		if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+			return s.tickets.redeem(ticket, time.Now())
+		}
+	}
+
+	if token == "" {
+		return principal{}, errors.New("missing token")
+	}
+
+	t, err := s.storer.APITokenByHash(auth.HashToken(token))
+	if err != nil {
+		return principal{}, fmt.Errorf("fail fetch token, err: %w", err)
+	}
+
+	if t.ID == 0 {
+		return principal{}, errors.New("unknown token")
+	}
+
+	if !t.RevokedAt.IsZero() {
+		return principal{}, fmt.Errorf("revoked token: %d", t.ID)
+	}
+
+	return principal{tokenID: t.ID, name: t.Name, role: t.Role}, nil
+}
+
+// redactedQuery returns request query without event ticket
+func redactedQuery(r *http.Request) string {
+	q := r.URL.Query()
+	if q.Get("ticket") != "" {
+		q.Set("ticket", "redacted")
+	}
+
+	return q.Encode()
+}
+
+// handleEventTicket issues a ticket to open event stream
+func (s *Server) handleEventTicket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	p, _ := r.Context().Value(principalKey{}).(principal)
+	ticket, err := s.tickets.issue(p, time.Now())
+	if err != nil {
+		s.logger.WithError(err).Error("fail issue event ticket")
+		writeError(w, http.StatusInternalServerError, "fail issue event ticket")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, eventTicketResponse{Ticket: ticket, ExpiresIn: int(eventTicketTTL.Seconds())})
+}
+
+// requestActor returns authenticated token owner of request
+func requestActor(r *http.Request) string {
+	p, _ := r.Context().Value(principalKey{}).(principal)
+
+	return p.name
+}
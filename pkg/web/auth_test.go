@@ -0,0 +1,127 @@
+package web
+
+import (
+	"bwd/pkg/auth"
+	"bwd/pkg/storage"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const testToken = "bwd_test"
+
+// fakeStorer knows one read only token and records audit logs, methods not overridden panic
+type fakeStorer struct {
+	storage.Storer
+	audits []storage.AuditLog
+}
+
+func (f *fakeStorer) APITokenByHash(hash string) (storage.APIToken, error) {
+	if hash != auth.HashToken(testToken) {
+		return storage.APIToken{}, nil
+	}
+
+	return storage.APIToken{ID: 1, Name: "dashboard", Role: auth.RoleReadOnly, Hash: hash}, nil
+}
+
+func (f *fakeStorer) AddAuditLog(entry storage.AuditLog) error {
+	f.audits = append(f.audits, entry)
+	return nil
+}
+
+func newTestServer() (*Server, *fakeStorer) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	st := &fakeStorer{}
+
+	return New(&ConfigServer{Storer: st}, logger), st
+}
+
+func serve(s *Server, method, target, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.routes().ServeHTTP(w, r)
+
+	return w
+}
+
+func issueTicket(t *testing.T, s *Server) string {
+	t.Helper()
+
+	w := serve(s, http.MethodPost, "/api/events/ticket", testToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected ticket issued, got: %d %s", w.Code, w.Body.String())
+	}
+
+	var resp eventTicketResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("fail decode ticket, err: %s", err)
+	}
+
+	return resp.Ticket
+}
+
+func TestEventStreamAuth(t *testing.T) {
+	s, _ := newTestServer()
+
+	if w := serve(s, http.MethodGet, "/api/events?token="+testToken, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected token query param refused, got: %d", w.Code)
+	}
+
+	// events bus is not set, an authenticated stream request ends as not found
+	ticket := issueTicket(t, s)
+	if w := serve(s, http.MethodGet, "/api/events?ticket="+ticket, ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected ticket accepted, got: %d", w.Code)
+	}
+	if w := serve(s, http.MethodGet, "/api/events?ticket="+ticket, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected ticket used once, got: %d", w.Code)
+	}
+
+	ticket = issueTicket(t, s)
+	if w := serve(s, http.MethodGet, "/api/apps?ticket="+ticket, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected ticket refused outside event stream, got: %d", w.Code)
+	}
+}
+
+func TestEventTicketExpires(t *testing.T) {
+	s, _ := newTestServer()
+	now := time.Now()
+
+	ticket, err := s.tickets.issue(principal{tokenID: 1}, now)
+	if err != nil {
+		t.Fatalf("fail issue ticket, err: %s", err)
+	}
+
+	if _, err := s.tickets.redeem(ticket, now.Add(eventTicketTTL+time.Second)); err == nil {
+		t.Error("expected expired ticket refused")
+	}
+}
+
+func TestReadsAudited(t *testing.T) {
+	s, st := newTestServer()
+
+	ticket := issueTicket(t, s)
+	serve(s, http.MethodGet, "/api/events?app=1&ticket="+ticket, "")
+
+	if len(st.audits) != 2 {
+		t.Fatalf("expected 2 audit logs, got: %d", len(st.audits))
+	}
+	for _, a := range st.audits {
+		if a.Action != auditActionRead || a.Actor != "dashboard" {
+			t.Errorf("expected read audited for dashboard, got: %+v", a)
+		}
+	}
+	if strings.Contains(st.audits[1].Details, ticket) {
+		t.Errorf("expected ticket redacted, got: %s", st.audits[1].Details)
+	}
+}
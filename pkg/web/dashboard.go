@@ -0,0 +1,21 @@
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// dashboardHandler serves embedded web ui, it reads data from the api
+func dashboardHandler() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		// embedded directory always exists
+		panic(err)
+	}
+
+	return http.StripPrefix("/dashboard/", http.FileServer(http.FS(files)))
+}
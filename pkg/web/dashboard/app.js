@@ -0,0 +1,297 @@
+// bwd dashboard, reads bwd http api and refreshes on trade events
+(function () {
+  'use strict';
+
+  var ACTIVE_STATUSES = [
+    'BUY_LIMIT', 'BUY_LIMIT_WANTS_PUBLISH', 'BUY_LIMIT_PUBLISHED', 'BUY_LIMIT_EXECUTED',
+    'SELL_LIMIT', 'SELL_LIMIT_WANTS_PUBLISH', 'SELL_LIMIT_PUBLISHED', 'SELL_LIMIT_EXECUTED',
+    'OPEN_SELL_LIMIT', 'OPEN_SELL_LIMIT_WANTS_PUBLISH', 'OPEN_SELL_LIMIT_PUBLISHED', 'OPEN_SELL_LIMIT_EXECUTED',
+    'CLOSE_BUY_LIMIT', 'CLOSE_BUY_LIMIT_WANTS_PUBLISH', 'CLOSE_BUY_LIMIT_PUBLISHED', 'CLOSE_BUY_LIMIT_EXECUTED'
+  ];
+  var MAX_ERRORS = 20;
+  var TOKEN_KEY = 'bwdToken';
+
+  var select = document.getElementById('app-select');
+  var apps = [];
+  var errors = [];
+  var stream = null;
+  var refreshTimer = null;
+  var streamTimer = null;
+
+  function api(path) {
+    return fetch(path, { headers: { Authorization: 'Bearer ' + token() } }).then(function (r) {
+      if (r.status === 401) {
+        localStorage.removeItem(TOKEN_KEY);
+      }
+      if (!r.ok) {
+        throw new Error(path + ': ' + r.status);
+      }
+      return r.json();
+    });
+  }
+
+  // api token is asked once and kept in browser storage, READ_ONLY is enough
+  function token() {
+    var t = localStorage.getItem(TOKEN_KEY);
+    if (!t) {
+      t = window.prompt('bwd api token') || '';
+      localStorage.setItem(TOKEN_KEY, t);
+    }
+    return t;
+  }
+
+  function el(tag, attrs, text) {
+    var e = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (k) { e.setAttribute(k, attrs[k]); });
+    if (text !== undefined) {
+      e.textContent = text;
+    }
+    return e;
+  }
+
+  function statusClass(status) {
+    if (/_EXECUTED$/.test(status)) {
+      return 'st-executed';
+    }
+    if (/_PUBLISHED$/.test(status)) {
+      return /^(BUY|CLOSE_BUY)/.test(status) ? 'st-buy' : 'st-sell';
+    }
+    return 'st-planned';
+  }
+
+  function addError(msg, at) {
+    errors.unshift({ msg: msg, at: at || new Date().toISOString() });
+    errors = errors.slice(0, MAX_ERRORS);
+    renderErrors();
+  }
+
+  function renderErrors() {
+    var ul = document.getElementById('errors');
+    ul.innerHTML = '';
+    var app = currentApp();
+    var list = errors.slice();
+    if (app && app.last_error) {
+      list.unshift({ msg: 'app ' + app.id + ': ' + app.last_error, at: app.last_error_at });
+    }
+    if (list.length === 0) {
+      ul.appendChild(el('li', { 'class': 'muted' }, 'no errors'));
+    }
+    list.forEach(function (e) {
+      ul.appendChild(el('li', {}, e.at + ' ' + e.msg));
+    });
+  }
+
+  function currentApp() {
+    var id = parseInt(select.value, 10);
+    return apps.filter(function (a) { return a.id === id; })[0];
+  }
+
+  function renderSummary(app) {
+    var s = document.getElementById('app-summary');
+    s.innerHTML = '';
+    if (!app) {
+      return;
+    }
+    var box = el('div', { 'class': 'summary' });
+    [
+      ['pair', app.base + '/' + app.quote],
+      ['status', app.status],
+      ['runtime', app.runtime_status + (app.running ? ' (running)' : '')],
+      ['connector', app.connector || app.exchange],
+      ['range', app.min_base_price + ' - ' + app.max_base_price],
+      ['direction', app.direction],
+      ['step volume', app.step_quote_volume],
+      ['published orders', app.publish_order_number]
+    ].forEach(function (kv) {
+      var d = el('div', {}, kv[0]);
+      d.appendChild(el('b', {}, String(kv[1])));
+      box.appendChild(d);
+    });
+    s.appendChild(box);
+  }
+
+  // every grid level is drawn, levels without active trade are empty, trades
+  // out of current grid (steps changed or app not running) are kept
+  function renderLadder(app, trades) {
+    var ladder = document.getElementById('ladder');
+    ladder.innerHTML = '';
+    var byPrice = {};
+    trades.forEach(function (t) { byPrice[String(t.open_base_price)] = t; });
+    var levels = (app.steps || []).map(String);
+    Object.keys(byPrice).forEach(function (price) {
+      if (levels.indexOf(price) === -1) {
+        levels.push(price);
+      }
+    });
+    levels.sort(function (a, b) { return Number(b) - Number(a); })
+      .forEach(function (price) {
+        var t = byPrice[price];
+        var row = el('div', { 'class': 'step ' + (t ? statusClass(t.status) : 'st-empty'), title: t ? t.status : 'no trade' });
+        row.appendChild(el('span', {}, price));
+        row.appendChild(el('span', {}, t ? t.status : ''));
+        row.appendChild(el('span', {}, t ? '→ ' + t.close_base_price : ''));
+        ladder.appendChild(row);
+      });
+    if (levels.length === 0) {
+      ladder.appendChild(el('div', { 'class': 'muted' }, 'no grid levels'));
+    }
+  }
+
+  function renderOrders(trades) {
+    var tbody = document.querySelector('#orders tbody');
+    tbody.innerHTML = '';
+    trades.filter(function (t) { return /_PUBLISHED$/.test(t.status); })
+      .forEach(function (t) {
+        var tr = el('tr');
+        [t.id, t.status, t.open_base_price, t.close_base_price, t.base_volume, t.buy_order_id || t.sell_order_id]
+          .forEach(function (v) { tr.appendChild(el('td', {}, String(v))); });
+        tbody.appendChild(tr);
+      });
+  }
+
+  function renderProfit(balance, app) {
+    var svg = document.getElementById('profit');
+    svg.innerHTML = '';
+    var history = balance.history.slice().reverse();
+    var sellBuy = app && app.direction === 'SELL_BUY';
+    var values = history.map(function (h) { return sellBuy ? h.total_base_net_income : h.total_net_income; });
+
+    if (values.length > 1) {
+      var min = Math.min.apply(null, values);
+      var max = Math.max.apply(null, values);
+      var span = max - min || 1;
+      var points = values.map(function (v, i) {
+        var x = i * 600 / (values.length - 1);
+        var y = 195 - (v - min) * 190 / span;
+        return x.toFixed(1) + ',' + y.toFixed(1);
+      }).join(' ');
+      var line = document.createElementNS('http://www.w3.org/2000/svg', 'polyline');
+      line.setAttribute('points', points);
+      svg.appendChild(line);
+    }
+
+    var t = balance.totals;
+    document.getElementById('profit-totals').textContent =
+      'net income ' + t.total_net_income + ', reinvested ' + t.total_reinvested +
+      ', reserved ' + t.total_reserved + ', withdrawn ' + t.total_withdrawn;
+  }
+
+  function loadApp() {
+    var app = currentApp();
+    renderSummary(app);
+    renderErrors();
+    if (!app) {
+      return;
+    }
+
+    api('/api/apps/' + app.id + '/trades?limit=1000&status=' + ACTIVE_STATUSES.join(','))
+      .then(function (trades) {
+        renderLadder(app, trades);
+        renderOrders(trades);
+      })
+      .catch(function (err) { addError(err.message); });
+
+    api('/api/apps/' + app.id + '/balance?limit=1000')
+      .then(function (balance) { renderProfit(balance, app); })
+      .catch(function (err) { addError(err.message); });
+  }
+
+  function loadApps() {
+    return api('/api/apps').then(function (list) {
+      apps = list;
+      var selected = select.value;
+      select.innerHTML = '';
+      apps.forEach(function (a) {
+        select.appendChild(el('option', { value: a.id }, a.id + ' ' + a.base + '/' + a.quote + ' ' + a.status));
+      });
+      if (selected) {
+        select.value = selected;
+      }
+    }).catch(function (err) { addError(err.message); });
+  }
+
+  // refresh is debounced, a trader run can emit many events
+  function scheduleRefresh() {
+    if (refreshTimer) {
+      return;
+    }
+    refreshTimer = setTimeout(function () {
+      refreshTimer = null;
+      loadApps().then(loadApp);
+    }, 1000);
+  }
+
+  function connectStream() {
+    if (stream) {
+      stream.close();
+      stream = null;
+    }
+    if (streamTimer) {
+      clearTimeout(streamTimer);
+      streamTimer = null;
+    }
+    var app = currentApp();
+    if (!app || !window.EventSource) {
+      return;
+    }
+
+    var status = document.getElementById('stream-status');
+    // stream url holds a one time ticket, api token never goes in url
+    fetch('/api/events/ticket', { method: 'POST', headers: { Authorization: 'Bearer ' + token() } })
+      .then(function (r) {
+        if (r.status === 401) {
+          localStorage.removeItem(TOKEN_KEY);
+        }
+        if (!r.ok) {
+          throw new Error('/api/events/ticket: ' + r.status);
+        }
+        return r.json();
+      })
+      .then(function (t) {
+        var current = currentApp();
+        if (current && current.id === app.id) {
+          openStream(app, t.ticket, status);
+        }
+      })
+      .catch(function () { reconnectStream(status); });
+  }
+
+  // ticket is used once, browser reconnect would reuse it so a new one is requested
+  function reconnectStream(status) {
+    status.textContent = 'stream: reconnecting';
+    if (!streamTimer) {
+      streamTimer = setTimeout(connectStream, 5000);
+    }
+  }
+
+  function openStream(app, ticket, status) {
+    stream = new EventSource('/api/events?app=' + app.id + '&ticket=' + encodeURIComponent(ticket));
+    var current = stream;
+    stream.onopen = function () { status.textContent = 'stream: live'; };
+    stream.onerror = function () {
+      current.close();
+      if (stream === current) {
+        reconnectStream(status);
+      }
+    };
+    ['TRADE_CREATED', 'ORDER_PUBLISHED', 'BUY_EXECUTED', 'SELL_EXECUTED', 'TRADE_CLOSED', 'REINVEST']
+      .forEach(function (type) { stream.addEventListener(type, scheduleRefresh); });
+    stream.addEventListener('ORDER_FAILED', function (msg) {
+      var e = JSON.parse(msg.data);
+      addError('trade ' + e.trade_id + ' ' + e.side + ' order failed: ' + e.error, e.created_at);
+    });
+  }
+
+  select.addEventListener('change', function () {
+    errors = [];
+    loadApp();
+    connectStream();
+  });
+
+  loadApps().then(function () {
+    loadApp();
+    connectStream();
+  });
+  // runtime status and errors are not streamed
+  setInterval(scheduleRefresh, 30000);
+})();
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// events buffered per stream client, older events are dropped for slow clients
+	eventsBufferSize = 256
+	// keeps idle streams open through proxies
+	eventsHeartbeat = 15 * time.Second
+)
+
+// handleEvents streams trade events as Server-Sent Events, app query param
+// filters by comma separated app ids
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if s.events == nil {
+		writeError(w, http.StatusNotFound, "event stream not available")
+		return
+	}
+
+	var appIDs []int
+	if v := r.URL.Query().Get("app"); v != "" {
+		for _, id := range strings.Split(v, ",") {
+			appID, err := strconv.Atoi(strings.TrimSpace(id))
+			if err != nil || appID < 1 {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid app id: %s", id))
+				return
+			}
+			appIDs = append(appIDs, appID)
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	sub := s.events.Subscribe(appIDs, eventsBufferSize)
+	defer sub.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				s.logger.WithError(err).Error("fail marshal event")
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
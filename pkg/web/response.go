@@ -0,0 +1,197 @@
+package web
+
+import (
+	"bwd/pkg/connector"
+	"bwd/pkg/ledger"
+	"bwd/pkg/storage"
+	"time"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type eventTicketResponse struct {
+	Ticket string `json:"ticket"`
+	// seconds the ticket can be used
+	ExpiresIn int `json:"expires_in"`
+}
+
+type appResponse struct {
+	ID                 int       `json:"id"`
+	Exchange           string    `json:"exchange"`
+	AccountID          int       `json:"account_id"`
+	Base               string    `json:"base"`
+	Quote              string    `json:"quote"`
+	Interval           string    `json:"interval"`
+	MarketOrderFees    float64   `json:"market_order_fees"`
+	LimitOrderFees     float64   `json:"limit_order_fees"`
+	MinBasePrice       float64   `json:"min_base_price"`
+	MaxBasePrice       float64   `json:"max_base_price"`
+	StepQuoteVolume    float64   `json:"step_quote_volume"`
+	StepsType          string    `json:"steps_type"`
+	StepsDetails       string    `json:"steps_details"`
+	CompoundType       string    `json:"compound_type"`
+	CompoundDetails    string    `json:"compound_details"`
+	PublishOrderNumber int       `json:"publish_order_number"`
+	Direction          string    `json:"direction"`
+	SkimPercent        float64   `json:"skim_percent"`
+	QuoteBudget        float64   `json:"quote_budget"`
+	QuotePercentUse    float64   `json:"quote_percent_use"`
+	StopPolicy         string    `json:"stop_policy"`
+	Status             string    `json:"status"`
+	IsDone             bool      `json:"is_done"`
+	RuntimeStatus      string    `json:"runtime_status"`
+	Running            bool      `json:"running"`
+	Connector          string    `json:"connector"`
+	Steps              []float64 `json:"steps"`
+	LastError          string    `json:"last_error"`
+	LastErrorAt        time.Time `json:"last_error_at"`
+	StartAttempts      int       `json:"start_attempts"`
+}
+
+func (s *Server) newAppResponse(a storage.App) appResponse {
+	return appResponse{
+		ID:                 a.ID,
+		Exchange:           a.Exchange,
+		AccountID:          a.AccountID,
+		Base:               a.Base,
+		Quote:              a.Quote,
+		Interval:           a.Interval.String(),
+		MarketOrderFees:    a.MarketOrderFees,
+		LimitOrderFees:     a.LimitOrderFees,
+		MinBasePrice:       a.MinBasePrice,
+		MaxBasePrice:       a.MaxBasePrice,
+		StepQuoteVolume:    a.StepQuoteVolume,
+		StepsType:          a.StepsType,
+		StepsDetails:       a.StepsDetails,
+		CompoundType:       a.CompoundType,
+		CompoundDetails:    a.CompoundDetails,
+		PublishOrderNumber: a.PublishOrderNumber,
+		Direction:          a.Direction,
+		SkimPercent:        a.SkimPercent,
+		QuoteBudget:        a.QuoteBudget,
+		QuotePercentUse:    a.QuotePercentUse,
+		StopPolicy:         a.StopPolicy,
+		Status:             a.Status,
+		IsDone:             a.IsDone,
+		RuntimeStatus:      a.RuntimeStatus,
+		Running:            s.state.IsAppRunning(a.ID),
+		Connector:          s.state.AppConnector(a.ID),
+		Steps:              s.state.AppSteps(a.ID),
+		LastError:          a.LastError,
+		LastErrorAt:        a.LastErrorAt,
+		StartAttempts:      a.StartAttempts,
+	}
+}
+
+type tradeResponse struct {
+	ID                   int       `json:"id"`
+	AppID                int       `json:"app_id"`
+	OpenBasePrice        float64   `json:"open_base_price"`
+	CloseBasePrice       float64   `json:"close_base_price"`
+	OpenType             string    `json:"open_type"`
+	CloseType            string    `json:"close_type"`
+	BaseVolume           float64   `json:"base_volume"`
+	CloseBaseVolume      float64   `json:"close_base_volume"`
+	BuyOrderID           string    `json:"buy_order_id"`
+	SellOrderID          string    `json:"sell_order_id"`
+	Status               string    `json:"status"`
+	ConvertedSellLimitAt time.Time `json:"converted_sell_limit_at"`
+	ClosedAt             time.Time `json:"closed_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+func newTradeResponse(t storage.Trade) tradeResponse {
+	return tradeResponse{
+		ID:                   t.ID,
+		AppID:                t.AppID,
+		OpenBasePrice:        t.OpenBasePrice,
+		CloseBasePrice:       t.CloseBasePrice,
+		OpenType:             t.OpenType,
+		CloseType:            t.CloseType,
+		BaseVolume:           t.BaseVolume,
+		CloseBaseVolume:      t.CloseBaseVolume,
+		BuyOrderID:           t.BuyOrderID,
+		SellOrderID:          t.SellOrderID,
+		Status:               t.Status,
+		ConvertedSellLimitAt: t.ConvertedSellLimitAt,
+		ClosedAt:             t.ClosedAt,
+		UpdatedAt:            t.UpdatedAt,
+		CreatedAt:            t.CreatedAt,
+	}
+}
+
+type totalsResponse struct {
+	TotalNetIncome  float64 `json:"total_net_income"`
+	TotalReinvested float64 `json:"total_reinvested"`
+	TotalReserved   float64 `json:"total_reserved"`
+	TotalWithdrawn  float64 `json:"total_withdrawn"`
+	Withdrawable    float64 `json:"withdrawable"`
+	NotAllocated    float64 `json:"not_allocated"`
+}
+
+func newTotalsResponse(r ledger.Report) totalsResponse {
+	return totalsResponse{
+		TotalNetIncome:  r.TotalNetIncome,
+		TotalReinvested: r.TotalReinvested,
+		TotalReserved:   r.TotalReserved,
+		TotalWithdrawn:  r.TotalWithdrawn,
+		Withdrawable:    r.Withdrawable,
+		NotAllocated:    r.NotAllocated,
+	}
+}
+
+type balanceHistoryResponse struct {
+	Action              string    `json:"action"`
+	QuoteVolume         float64   `json:"quote_volume"`
+	TotalNetIncome      float64   `json:"total_net_income"`
+	TotalReinvested     float64   `json:"total_reinvested"`
+	TotalReserved       float64   `json:"total_reserved"`
+	TotalWithdrawn      float64   `json:"total_withdrawn"`
+	BaseVolume          float64   `json:"base_volume"`
+	TotalBaseNetIncome  float64   `json:"total_base_net_income"`
+	TotalBaseReinvested float64   `json:"total_base_reinvested"`
+	TradeID             int       `json:"trade_id"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+func newBalanceHistoryResponse(h storage.BalanceHistory) balanceHistoryResponse {
+	return balanceHistoryResponse{
+		Action:              h.Action,
+		QuoteVolume:         h.QuoteVolume,
+		TotalNetIncome:      h.TotalNetIncome,
+		TotalReinvested:     h.TotalReinvested,
+		TotalReserved:       h.TotalReserved,
+		TotalWithdrawn:      h.TotalWithdrawn,
+		BaseVolume:          h.BaseVolume,
+		TotalBaseNetIncome:  h.TotalBaseNetIncome,
+		TotalBaseReinvested: h.TotalBaseReinvested,
+		TradeID:             h.InternalTradeID,
+		CreatedAt:           h.CreatedAt,
+	}
+}
+
+type balanceResponse struct {
+	Totals  totalsResponse           `json:"totals"`
+	History []balanceHistoryResponse `json:"history"`
+}
+
+type connectorResponse struct {
+	Connector         string    `json:"connector"`
+	StartedAt         time.Time `json:"started_at"`
+	LastSyncAt        time.Time `json:"last_sync_at"`
+	ConsecutiveErrors int       `json:"consecutive_errors"`
+	TotalErrors       int       `json:"total_errors"`
+}
+
+func newConnectorResponse(key string, h connector.Health) connectorResponse {
+	return connectorResponse{
+		Connector:         key,
+		StartedAt:         h.StartedAt,
+		LastSyncAt:        h.LastSyncAt,
+		ConsecutiveErrors: h.ConsecutiveErrors,
+		TotalErrors:       h.TotalErrors,
+	}
+}
@@ -0,0 +1,193 @@
+package web
+
+import (
+	"bwd/pkg/ledger"
+	"bwd/pkg/storage"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/apps", s.route("apps", s.handleApps))
+	mux.HandleFunc("/api/apps/validate", s.route("app_validate", s.handleValidateApp))
+	mux.HandleFunc("/api/apps/", s.route("app", s.handleApp))
+	mux.HandleFunc("/api/connectors", s.route("connectors", s.handleConnectors))
+	mux.HandleFunc("/api/events", s.route("events", s.handleEvents))
+	mux.HandleFunc("/api/events/ticket", s.route("event_ticket", s.handleEventTicket))
+	// dashboard static files hold no data, dashboard calls api with a token
+	mux.HandleFunc("/dashboard/", s.instrument("dashboard", dashboardHandler().ServeHTTP))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		http.Redirect(w, r, "/dashboard/", http.StatusFound)
+	})
+
+	return mux
+}
+
+// handleApps lists apps with runtime status, POST creates an app
+func (s *Server) handleApps(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		s.handleCreateApp(w, r)
+		return
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	apps, err := s.storer.Apps()
+	if err != nil {
+		s.logger.WithError(err).Error("fail fetch apps")
+		writeError(w, http.StatusInternalServerError, "fail fetch apps")
+		return
+	}
+
+	resp := make([]appResponse, 0, len(apps))
+	for _, a := range apps {
+		resp = append(resp, s.newAppResponse(a))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleApp serves GET /api/apps/{id}, /api/apps/{id}/trades, /api/apps/{id}/balance
+// and admin PUT /api/apps/{id}/status, PUT /api/apps/{id}/grid
+func (s *Server) handleApp(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/apps/"), "/"), "/")
+	appID, err := strconv.Atoi(parts[0])
+	if err != nil || appID < 1 {
+		writeError(w, http.StatusBadRequest, "invalid app id")
+		return
+	}
+
+	appCfg, found, err := s.app(appID)
+	if err != nil {
+		s.logger.WithError(err).WithField("appid", appID).Error("fail fetch app")
+		writeError(w, http.StatusInternalServerError, "fail fetch app")
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "app not found")
+		return
+	}
+
+	route := r.Method + " " + strings.Join(parts[1:], "/")
+	switch route {
+	case "GET ":
+		writeJSON(w, http.StatusOK, s.newAppResponse(appCfg))
+	case "GET trades":
+		s.handleAppTrades(w, r, appID)
+	case "GET balance":
+		s.handleAppBalance(w, r, appID)
+	case "PUT status":
+		s.handleAppStatus(w, r, appCfg)
+	case "PUT grid":
+		s.handleAppGrid(w, r, appCfg)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleAppTrades lists app trades, filtered by comma separated status query param
+func (s *Server) handleAppTrades(w http.ResponseWriter, r *http.Request, appID int) {
+	limit, err := queryLimit(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var statuses []string
+	if v := r.URL.Query().Get("status"); v != "" {
+		for _, status := range strings.Split(v, ",") {
+			statuses = append(statuses, strings.ToUpper(strings.TrimSpace(status)))
+		}
+	}
+
+	trades, err := s.storer.Trades(appID, statuses, limit)
+	if err != nil {
+		s.logger.WithError(err).WithField("appid", appID).Error("fail fetch trades")
+		writeError(w, http.StatusInternalServerError, "fail fetch trades")
+		return
+	}
+
+	resp := make([]tradeResponse, 0, len(trades))
+	for _, t := range trades {
+		resp = append(resp, newTradeResponse(t))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleAppBalance returns current app totals and latest balance history
+func (s *Server) handleAppBalance(w http.ResponseWriter, r *http.Request, appID int) {
+	limit, err := queryLimit(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := ledger.New(s.storer).AppReport(appID)
+	if err != nil {
+		s.logger.WithError(err).WithField("appid", appID).Error("fail fetch app report")
+		writeError(w, http.StatusInternalServerError, "fail fetch balance")
+		return
+	}
+
+	history, err := s.storer.BalanceHistory(appID, limit)
+	if err != nil {
+		s.logger.WithError(err).WithField("appid", appID).Error("fail fetch balance history")
+		writeError(w, http.StatusInternalServerError, "fail fetch balance")
+		return
+	}
+
+	resp := balanceResponse{
+		Totals:  newTotalsResponse(report),
+		History: make([]balanceHistoryResponse, 0, len(history)),
+	}
+	for _, h := range history {
+		resp.History = append(resp.History, newBalanceHistoryResponse(h))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleConnectors returns health of running connectors
+func (s *Server) handleConnectors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	resp := make([]connectorResponse, 0)
+	for key, h := range s.state.ConnectorsHealth() {
+		resp = append(resp, newConnectorResponse(key, h))
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Connector < resp[j].Connector
+	})
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// app returns app from storage, found is false when missing
+func (s *Server) app(appID int) (storage.App, bool, error) {
+	apps, err := s.storer.Apps()
+	if err != nil {
+		return storage.App{}, false, err
+	}
+
+	for _, a := range apps {
+		if a.ID == appID {
+			return a, true, nil
+		}
+	}
+
+	return storage.App{}, false, nil
+}
@@ -0,0 +1,187 @@
+package web
+
+import (
+	"bwd/pkg/connector"
+	"bwd/pkg/event"
+	"bwd/pkg/storage"
+	"bwd/pkg/utils/metrics/exporter"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	shutdownTimeout = 5 * time.Second
+	// default and max rows returned by list endpoints
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+var (
+	metricRequestLatency = exporter.GetHistogram("bwd", "web_request_ms_latency", []string{"route", "code"})
+)
+
+// State is orchestrator runtime state exposed by api
+type State interface {
+	IsAppRunning(appID int) bool
+	AppConnector(appID int) string
+	// AppSteps returns grid levels of running app, nil when app is not running
+	AppSteps(appID int) []float64
+	ConnectorsHealth() map[string]connector.Health
+	// ValidateApp checks app config against exchange without starting it
+	ValidateApp(appCfg storage.App) error
+}
+
+type ConfigServer struct {
+	Port   string
+	Storer storage.Storer
+	State  State
+	// Events is streamed to clients, optional
+	Events *event.Bus
+}
+
+type Server struct {
+	logger logrus.FieldLogger
+	storer storage.Storer
+	state  State
+	port   string
+	events *event.Bus
+	srv    *http.Server
+	// event stream tickets issued to authenticated clients
+	tickets *eventTickets
+	// closed on shutdown, ends event streams
+	done chan struct{}
+}
+
+func New(cfg *ConfigServer, logger logrus.FieldLogger) *Server {
+	s := &Server{
+		logger: logger.WithField("module", "web"),
+		storer: cfg.Storer,
+		state:  cfg.State,
+		port:   cfg.Port,
+		events: cfg.Events,
+		tickets: &eventTickets{
+			tickets: make(map[string]eventTicket),
+		},
+		done: make(chan struct{}),
+	}
+
+	// no write timeout, event streams keep responses open
+	s.srv = &http.Server{
+		Addr:        ":" + cfg.Port,
+		Handler:     s.routes(),
+		ReadTimeout: 10 * time.Second,
+	}
+	s.srv.RegisterOnShutdown(func() {
+		close(s.done)
+	})
+
+	return s
+}
+
+// Start listens on port and serves requests in background
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return fmt.Errorf("web listen on port %s fail, err: %w", s.port, err)
+	}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			s.logger.WithError(err).Error("web server stopped")
+		}
+	}()
+
+	s.logger.WithField("port", s.port).Info("web server listening")
+
+	return nil
+}
+
+// Stop waits in flight requests, up to shutdown timeout
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.srv.Shutdown(ctx); err != nil {
+		s.logger.WithError(err).Error("fail graceful web server shutdown")
+	}
+}
+
+// route serves an api handler, api token is required
+func (s *Server) route(name string, h http.HandlerFunc) http.HandlerFunc {
+	return s.instrument(name, s.authorize(h))
+}
+
+// instrument wraps handler with latency metric and panic recovery
+func (s *Server) instrument(name string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTimeMs := time.Now().UnixNano() / int64(time.Millisecond)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		defer func() {
+			if p := recover(); p != nil {
+				s.logger.WithField("route", name).Errorf("web handler panic: %v", p)
+				writeError(rec, http.StatusInternalServerError, "internal error")
+			}
+
+			labels := prometheus.Labels{"route": name, "code": strconv.Itoa(rec.status)}
+			endTimeMs := time.Now().UnixNano() / int64(time.Millisecond)
+			metricRequestLatency.With(labels).Observe(float64(endTimeMs - startTimeMs))
+		}()
+
+		h(rec, r)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush is needed by event streams
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
+
+// queryLimit parses limit query param, bounded by maxLimit
+func queryLimit(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return defaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("invalid limit: %s", v)
+	}
+
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	return limit, nil
+}